@@ -0,0 +1,508 @@
+// Package cmd provides an embeddable command line interface for a mygrate.Service.
+//
+// A binary can ship the standard subcommands with a few lines:
+//
+//	func main() {
+//		myg := mygrate.New()
+//		migrations.Register(myg)
+//		cmd.Main(myg)
+//	}
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/lanz-dev/go-mygrate/mygrate"
+	"github.com/lanz-dev/go-mygrate/scaffold"
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK    = 0 // ExitOK means the command was successful.
+	ExitError = 1 // ExitError means the command failed.
+	ExitUsage = 2 // ExitUsage means the command line was invalid.
+)
+
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+const usage = `Usage: %s <command> [flags]
+
+Commands:
+  up      execute outstanding migrations
+  down    rollback migrations, default is the last applied migration
+  status  show applied, pending and unknown migrations
+  redo    rollback the last applied migration and execute it again
+  reset   rollback all migrations
+  repair  accept the changed checksums of applied migrations
+  history show the history of all executions
+  create  create a new migration from a template
+  help    show this help
+
+Run "%s <command> -h" to show the flags of a command.
+`
+
+var (
+	errUsage = errors.New("invalid usage")
+	// errFlags is returned for errors which the flag package has already printed.
+	errFlags = errors.New("invalid flags")
+)
+
+// Command is the command line interface of a mygrate.Service.
+type Command struct {
+	service *mygrate.Service
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+// Option configures a Command.
+type Option func(c *Command)
+
+// WithOutput sets the writers for the regular output and for errors.
+// Default is os.Stdout and os.Stderr.
+func WithOutput(stdout, stderr io.Writer) Option {
+	return func(c *Command) {
+		c.stdout = stdout
+		c.stderr = stderr
+	}
+}
+
+// New returns a Command for the configured service.
+func New(service *mygrate.Service, opts ...Option) *Command {
+	c := &Command{
+		service: service,
+		stdout:  os.Stdout,
+		stderr:  os.Stderr,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Main runs the command line of the process and exits with its exit code.
+// SIGINT and SIGTERM cancel the context of the running migration.
+func Main(service *mygrate.Service, opts ...Option) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := New(service, opts...).Run(ctx, os.Args)
+	stop()
+	os.Exit(code)
+}
+
+// Run executes the command line args, where args[0] is the name of the program
+// like in os.Args. It returns one of the exit codes.
+func (c *Command) Run(ctx context.Context, args []string) int {
+	name := "mygrate"
+	if len(args) > 0 {
+		name = filepath.Base(args[0])
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		fmt.Fprintf(c.stderr, usage, name, name)
+		return ExitUsage
+	}
+
+	commands := map[string]func(context.Context, *flag.FlagSet, []string) error{
+		"up":      c.up,
+		"down":    c.down,
+		"status":  c.status,
+		"redo":    c.redo,
+		"reset":   c.reset,
+		"repair":  c.repair,
+		"history": c.history,
+		"create":  c.create,
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+			fmt.Fprintf(c.stdout, usage, name, name)
+			return ExitOK
+		}
+		fmt.Fprintf(c.stderr, "unknown command %q\n\n", args[0])
+		fmt.Fprintf(c.stderr, usage, name, name)
+		return ExitUsage
+	}
+
+	fs := flag.NewFlagSet(name+" "+args[0], flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+
+	err := command(ctx, fs, args[1:])
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.Is(err, errFlags):
+		return ExitUsage
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(c.stderr, "%s\n", err)
+		fs.Usage()
+		return ExitUsage
+	default:
+		fmt.Fprintf(c.stderr, "error: %s\n", err)
+		return ExitError
+	}
+}
+
+// parse parses args, errors of the flag package are wrapped with errFlags.
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %s", errFlags, err)
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected arguments %v", errUsage, fs.Args())
+	}
+	return nil
+}
+
+// exclusive returns an error if more than one of the given flags is set.
+func exclusive(fs *flag.FlagSet, names ...string) error {
+	var set []string
+	fs.Visit(func(f *flag.Flag) {
+		for _, name := range names {
+			if f.Name == name {
+				set = append(set, "-"+name)
+			}
+		}
+	})
+	if len(set) > 1 {
+		return fmt.Errorf("%w: %v are exclusive", errUsage, set)
+	}
+	return nil
+}
+
+// isSet reports if the flag name was set on the command line.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func formatFlag(fs *flag.FlagSet) *string {
+	return fs.String("format", formatText, "output format: text or json")
+}
+
+func checkFormat(format string) error {
+	if format != formatText && format != formatJSON {
+		return fmt.Errorf("%w: unknown format %q", errUsage, format)
+	}
+	return nil
+}
+
+func (c *Command) up(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	to := fs.String("to", "", "migrate up to (including) this migration id")
+	steps := fs.Int("steps", 0, "migrate only the next n migrations")
+	redoLast := fs.Bool("redo-last", false, "redo the last migration if nothing is outstanding")
+	dryRun := fs.Bool("dry-run", false, "print the plan without executing it")
+	format := formatFlag(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	if err := exclusive(fs, "to", "steps", "redo-last"); err != nil {
+		return err
+	}
+	stepsSet := isSet(fs, "steps")
+	if stepsSet && *steps < 1 {
+		return fmt.Errorf("%w: -steps has to be positive", errUsage)
+	}
+
+	if *dryRun {
+		var plan mygrate.Plan
+		var err error
+		switch {
+		case *to != "":
+			plan, err = c.service.PlanMigrateToContext(ctx, *to)
+		case stepsSet:
+			plan, err = c.service.PlanStepsContext(ctx, *steps)
+		default:
+			plan, err = c.service.PlanMigrateContext(ctx, *redoLast)
+		}
+		if err != nil {
+			return err
+		}
+		return c.printPlan(*format, plan)
+	}
+
+	var n int
+	var err error
+	switch {
+	case *to != "":
+		n, err = c.service.MigrateToContext(ctx, *to)
+	case stepsSet:
+		n, err = c.service.StepsContext(ctx, *steps)
+	default:
+		n, err = c.service.MigrateContext(ctx, *redoLast)
+	}
+	if err != nil {
+		return err
+	}
+	return c.printResult(*format, "up", n)
+}
+
+func (c *Command) down(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	to := fs.String("to", "", "rollback down to (including) this migration id")
+	steps := fs.Int("steps", 1, "rollback the last n applied migrations")
+	dryRun := fs.Bool("dry-run", false, "print the plan without executing it")
+	format := formatFlag(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+	if err := exclusive(fs, "to", "steps"); err != nil {
+		return err
+	}
+	if *steps < 1 {
+		return fmt.Errorf("%w: -steps has to be positive", errUsage)
+	}
+
+	if *dryRun {
+		var plan mygrate.Plan
+		var err error
+		if *to != "" {
+			plan, err = c.service.PlanRollbackContext(ctx, *to)
+		} else {
+			plan, err = c.service.PlanStepsContext(ctx, -*steps)
+		}
+		if err != nil {
+			return err
+		}
+		return c.printPlan(*format, plan)
+	}
+
+	if *to != "" {
+		n, err := c.service.RollbackToContext(ctx, *to)
+		if err != nil {
+			return err
+		}
+		return c.printResult(*format, "down", n)
+	}
+
+	n, err := c.service.StepsContext(ctx, -*steps)
+	if err != nil {
+		return err
+	}
+	return c.printResult(*format, "down", n)
+}
+
+func (c *Command) status(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	format := formatFlag(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+
+	report, err := c.service.StatusContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if *format == formatJSON {
+		return c.printJSON(report)
+	}
+	_, err = io.WriteString(c.stdout, report.String())
+	return err
+}
+
+func (c *Command) redo(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	dryRun := fs.Bool("dry-run", false, "print the plan without executing it")
+	format := formatFlag(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+
+	if *dryRun {
+		plan, err := c.service.PlanRedoContext(ctx)
+		if err != nil {
+			return err
+		}
+		return c.printPlan(*format, plan)
+	}
+
+	redone, err := c.service.RedoContext(ctx)
+	if err != nil {
+		return err
+	}
+	n := 0
+	if redone {
+		n = 1
+	}
+	return c.printResult(*format, "redo", n)
+}
+
+func (c *Command) reset(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	dryRun := fs.Bool("dry-run", false, "print the plan without executing it")
+	format := formatFlag(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+
+	if *dryRun {
+		plan, err := c.service.PlanResetContext(ctx)
+		if err != nil {
+			return err
+		}
+		return c.printPlan(*format, plan)
+	}
+
+	n, err := c.service.RollbackAllContext(ctx)
+	if err != nil {
+		return err
+	}
+	return c.printResult(*format, "reset", n)
+}
+
+func (c *Command) repair(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	format := formatFlag(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+
+	n, err := c.service.RepairContext(ctx)
+	if err != nil {
+		return err
+	}
+	return c.printResult(*format, "repair", n)
+}
+
+func (c *Command) history(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	format := formatFlag(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format); err != nil {
+		return err
+	}
+
+	history, err := c.service.HistoryContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if *format == formatJSON {
+		if history == nil {
+			history = []store.HistoryEntry{}
+		}
+		return c.printJSON(history)
+	}
+	for _, e := range history {
+		line := fmt.Sprintf("%-25s %-6s %s (%s)", e.Executed.Format(time.RFC3339), e.Event, e.ID, e.Reason)
+		if e.Error != "" {
+			line += ": " + e.Error
+		}
+		if _, err := fmt.Fprintln(c.stdout, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Command) create(_ context.Context, fs *flag.FlagSet, args []string) error {
+	dir := fs.String("dir", "migrations", "directory of the migrations")
+	sql := fs.Bool("sql", false, "create an up and a down SQL script instead of a Go file")
+	timestamp := fs.Bool("timestamp", false, "prefix with a timestamp instead of a sequential number")
+	registerFile := fs.String("register-file", "migrations.go", "Go file in -dir with the Register func")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s [flags] <name>:\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %s", errFlags, err)
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%w: expected exactly one name", errUsage)
+	}
+
+	cfg := scaffold.Config{
+		Dir:          *dir,
+		Name:         fs.Arg(0),
+		Timestamp:    *timestamp,
+		RegisterFile: *registerFile,
+	}
+	if *sql {
+		cfg.Kind = scaffold.KindSQL
+	}
+
+	files, err := scaffold.Create(cfg)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if _, err := fmt.Fprintf(c.stdout, "%s\n", file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Command) printPlan(format string, plan mygrate.Plan) error {
+	if format == formatJSON {
+		if plan == nil {
+			plan = mygrate.Plan{}
+		}
+		return c.printJSON(plan)
+	}
+
+	_, err := io.WriteString(c.stdout, plan.String())
+	return err
+}
+
+func (c *Command) printResult(format string, command string, n int) error {
+	if format == formatJSON {
+		return c.printJSON(struct {
+			Command    string `json:"command"`
+			Migrations int    `json:"migrations"`
+		}{command, n})
+	}
+
+	_, err := fmt.Fprintf(c.stdout, "%s: %d migration(s)\n", command, n)
+	return err
+}
+
+func (c *Command) printJSON(v interface{}) error {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,136 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lanz-dev/go-mygrate/cmd"
+	"github.com/lanz-dev/go-mygrate/mygrate"
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+func nilFunc() error {
+	return nil
+}
+
+// run executes the command line against a service with the migrations 1, 2
+// and 3, where 1 is already applied.
+func run(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+
+	mem := store.NewMemoryStore()
+	if err := mem.Up("1", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	m := mygrate.New(mygrate.WithStore(mem))
+	m.Register("1", nilFunc, nilFunc)
+	m.Register("2", nilFunc, nilFunc)
+	m.Register("3", nilFunc, nilFunc)
+
+	var stdout, stderr bytes.Buffer
+	c := cmd.New(m, cmd.WithOutput(&stdout, &stderr))
+	code := c.Run(context.Background(), append([]string{"migrate"}, args...))
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		args   []string
+		code   int
+		stdout string
+	}{
+		"up":              {args: []string{"up"}, code: cmd.ExitOK, stdout: "up: 2 migration(s)\n"},
+		"up to":           {args: []string{"up", "-to", "2"}, code: cmd.ExitOK, stdout: "up: 1 migration(s)\n"},
+		"up steps":        {args: []string{"up", "-steps", "1"}, code: cmd.ExitOK, stdout: "up: 1 migration(s)\n"},
+		"up zero steps":   {args: []string{"up", "-steps", "0"}, code: cmd.ExitUsage},
+		"up dry run zero": {args: []string{"up", "-steps", "0", "-dry-run"}, code: cmd.ExitUsage},
+		"up dry run":      {args: []string{"up", "-dry-run"}, code: cmd.ExitOK, stdout: "up   2 (pending)\nup   3 (pending)\n"},
+		"down":            {args: []string{"down"}, code: cmd.ExitOK, stdout: "down: 1 migration(s)\n"},
+		"down to":         {args: []string{"down", "-to", "1"}, code: cmd.ExitOK, stdout: "down: 1 migration(s)\n"},
+		"down dry run":    {args: []string{"down", "-to", "1", "-dry-run"}, code: cmd.ExitOK, stdout: "down 1 (rollback to 1)\n"},
+		"down unknown id": {args: []string{"down", "-to", "4"}, code: cmd.ExitError},
+		"redo":            {args: []string{"redo"}, code: cmd.ExitOK, stdout: "redo: 1 migration(s)\n"},
+		"reset":           {args: []string{"reset"}, code: cmd.ExitOK, stdout: "reset: 1 migration(s)\n"},
+		"repair":          {args: []string{"repair"}, code: cmd.ExitOK, stdout: "repair: 0 migration(s)\n"},
+		"history":         {args: []string{"history", "-format", "json"}, code: cmd.ExitOK, stdout: "[]\n"},
+		"help":            {args: []string{"help"}, code: cmd.ExitOK},
+		"no command":      {args: []string{}, code: cmd.ExitUsage},
+		"unknown command": {args: []string{"sideways"}, code: cmd.ExitUsage},
+		"unknown flag":    {args: []string{"up", "-unknown"}, code: cmd.ExitUsage},
+		"exclusive flags": {args: []string{"up", "-to", "2", "-steps", "1"}, code: cmd.ExitUsage},
+		"unknown format":  {args: []string{"status", "-format", "xml"}, code: cmd.ExitUsage},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			code, stdout, stderr := run(t, tt.args...)
+			if code != tt.code {
+				t.Fatalf(`expected exit code '%d', got '%d' with '%s'`, tt.code, code, stderr)
+			}
+			if tt.stdout != "" && stdout != tt.stdout {
+				t.Fatalf(`expected output '%s', got '%s'`, tt.stdout, stdout)
+			}
+		})
+	}
+}
+
+func TestCommand_Run_StatusJSON(t *testing.T) {
+	t.Parallel()
+
+	code, stdout, stderr := run(t, "status", "-format", "json")
+	if code != cmd.ExitOK {
+		t.Fatalf(`expected exit code '%d', got '%d' with '%s'`, cmd.ExitOK, code, stderr)
+	}
+
+	var report mygrate.StatusReport
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if len(report.Applied()) != 1 || len(report.Pending()) != 2 {
+		t.Fatalf(`expected 1 applied and 2 pending migrations, got '%s'`, stdout)
+	}
+}
+
+func TestCommand_Run_Help(t *testing.T) {
+	t.Parallel()
+
+	_, stdout, _ := run(t, "help")
+	for _, command := range []string{"up", "down", "status", "redo", "reset", "repair", "history"} {
+		if !strings.Contains(stdout, "  "+command+" ") {
+			t.Fatalf(`expected help to contain command '%s', got '%s'`, command, stdout)
+		}
+	}
+}
+
+func TestCommand_Run_Create(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	code, stdout, stderr := run(t, "create", "-sql", "-dir", dir, "add_users")
+	if code != cmd.ExitOK {
+		t.Fatalf(`expected exit code '%d', got '%d' with '%s'`, cmd.ExitOK, code, stderr)
+	}
+	if !strings.Contains(stdout, "0001_add_users.up.sql") || !strings.Contains(stdout, "0001_add_users.down.sql") {
+		t.Fatalf(`expected created files, got '%s'`, stdout)
+	}
+
+	code, _, _ = run(t, "create", "-sql", "-dir", dir, "add_users")
+	if code != cmd.ExitError {
+		t.Fatalf(`expected exit code '%d' for a duplicate, got '%d'`, cmd.ExitError, code)
+	}
+
+	code, _, _ = run(t, "create", "-dir", dir)
+	if code != cmd.ExitUsage {
+		t.Fatalf(`expected exit code '%d' without a name, got '%d'`, cmd.ExitUsage, code)
+	}
+}
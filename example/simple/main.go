@@ -1,20 +1,22 @@
 package main
 
 import (
+	"log"
+
+	"github.com/lanz-dev/go-mygrate/cmd"
 	"github.com/lanz-dev/go-mygrate/example/simple/migrations"
 	"github.com/lanz-dev/go-mygrate/mygrate"
 )
 
 func main() {
 	myg := mygrate.New( // New will use a Default FileStore with the Path .mygrate
-	// custom Store:
-	// mygrate.WithStore(store.NewFileStoreWithPath(".migration")),
+		// custom Store:
+		// mygrate.WithStore(store.NewFileStoreWithPath(".migration")),
+		mygrate.WithLogger(mygrate.NewStdLogger(log.Default())),
 	)
 
 	migrations.Register(myg)
 
-	const redoLast = true // redoLast migration
-	if _, err := myg.Migrate(redoLast); err != nil {
-		panic(err)
-	}
+	// e.g. "go run . up", "go run . status" or "go run . redo"
+	cmd.Main(myg)
 }
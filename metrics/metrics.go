@@ -0,0 +1,214 @@
+// Package metrics collects metrics of a mygrate.Service and exposes them in
+// the Prometheus text format, without any dependency:
+//
+//	myg := mygrate.New()
+//	http.Handle("/metrics", metrics.New(myg))
+//
+// The number of applied and pending migrations is read from the store on each
+// scrape with mygrate.Service.ReadStatusContext, so it is reported even if the
+// service never migrates itself, but the store is never initialized by a scrape.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lanz-dev/go-mygrate/mygrate"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the migration duration histogram.
+var DefaultBuckets = []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300}
+
+// Collector tracks the runs of a mygrate.Service and implements http.Handler.
+type Collector struct {
+	service *mygrate.Service
+	buckets []float64
+
+	mu          sync.Mutex
+	lastRun     time.Time
+	lastSuccess bool
+	lockWait    time.Duration
+	durations   map[mygrate.Direction]*histogram
+	failures    map[mygrate.Direction]int
+}
+
+type histogram struct {
+	counts []int // counts per bucket, not cumulative
+	sum    float64
+	count  int
+}
+
+// Option configures a Collector.
+type Option func(c *Collector)
+
+// WithBuckets sets the upper bounds in seconds of the migration duration histogram.
+func WithBuckets(buckets []float64) Option {
+	return func(c *Collector) {
+		c.buckets = append([]float64(nil), buckets...)
+		sort.Float64s(c.buckets)
+	}
+}
+
+// New returns a Collector, which is attached to service as mygrate.Observer.
+func New(service *mygrate.Service, opts ...Option) *Collector {
+	c := &Collector{
+		service:   service,
+		buckets:   DefaultBuckets,
+		durations: map[mygrate.Direction]*histogram{},
+		failures:  map[mygrate.Direction]int{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	mygrate.WithObserver(c)(service)
+
+	return c
+}
+
+// Observe implements mygrate.Observer.
+func (c *Collector) Observe(ctx context.Context, e mygrate.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch e.Type {
+	case mygrate.EventLockAcquired:
+		c.lockWait = e.Duration
+	case mygrate.EventStoreUpdated:
+		c.observeDuration(e.Direction, e.Duration)
+	case mygrate.EventMigrationFailed:
+		c.failures[e.Direction]++
+	case mygrate.EventRunFinished:
+		c.lastRun = e.Time
+		c.lastSuccess = e.Err == nil
+	}
+}
+
+func (c *Collector) observeDuration(dir mygrate.Direction, d time.Duration) {
+	h, ok := c.durations[dir]
+	if !ok {
+		h = &histogram{counts: make([]int, len(c.buckets))}
+		c.durations[dir] = h
+	}
+
+	seconds := d.Seconds()
+	for i, le := range c.buckets {
+		if seconds <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// ServeHTTP implements http.Handler.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = c.write(r.Context(), w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	return c.write(context.Background(), w)
+}
+
+// write reads the status with ctx and writes the metrics. The applied and
+// pending migrations are left out if the status can not be read.
+func (c *Collector) write(ctx context.Context, w io.Writer) (int64, error) {
+	report, statusErr := c.service.ReadStatusContext(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := &printer{w: w}
+
+	if statusErr == nil {
+		p.metric("mygrate_migrations_applied", "gauge", "Number of applied migrations.")
+		p.sample("mygrate_migrations_applied", "", float64(len(report.Applied())))
+		p.metric("mygrate_migrations_pending", "gauge", "Number of pending migrations.")
+		p.sample("mygrate_migrations_pending", "", float64(len(report.Pending())))
+	}
+
+	if !c.lastRun.IsZero() {
+		p.metric("mygrate_last_run_timestamp_seconds", "gauge", "Unix time of the last finished run.")
+		p.sample("mygrate_last_run_timestamp_seconds", "", float64(c.lastRun.UnixNano())/1e9)
+		p.metric("mygrate_last_run_success", "gauge", "Whether the last run succeeded.")
+		p.sample("mygrate_last_run_success", "", boolValue(c.lastSuccess))
+		p.metric("mygrate_lock_wait_seconds", "gauge", "Time the last run waited for the lock.")
+		p.sample("mygrate_lock_wait_seconds", "", c.lockWait.Seconds())
+	}
+
+	p.metric("mygrate_migration_failures_total", "counter", "Number of failed migrations.")
+	for _, dir := range []mygrate.Direction{mygrate.DirectionUp, mygrate.DirectionDown} {
+		p.sample("mygrate_migration_failures_total", label("direction", string(dir)), float64(c.failures[dir]))
+	}
+
+	p.metric("mygrate_migration_duration_seconds", "histogram", "Duration of successful migrations.")
+	for _, dir := range []mygrate.Direction{mygrate.DirectionUp, mygrate.DirectionDown} {
+		h, ok := c.durations[dir]
+		if !ok {
+			continue
+		}
+		dirLabel := label("direction", string(dir))
+		cumulative := 0
+		for i, le := range c.buckets {
+			cumulative += h.counts[i]
+			p.sample("mygrate_migration_duration_seconds_bucket", dirLabel+","+label("le", formatFloat(le)), float64(cumulative))
+		}
+		p.sample("mygrate_migration_duration_seconds_bucket", dirLabel+","+label("le", "+Inf"), float64(h.count))
+		p.sample("mygrate_migration_duration_seconds_sum", dirLabel, h.sum)
+		p.sample("mygrate_migration_duration_seconds_count", dirLabel, float64(h.count))
+	}
+
+	return p.n, p.err
+}
+
+// printer writes lines of the text format and keeps the first error.
+type printer struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (p *printer) printf(format string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(p.w, format, args...)
+	p.n += int64(n)
+	p.err = err
+}
+
+func (p *printer) metric(name, typ, help string) {
+	p.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func (p *printer) sample(name, labels string, value float64) {
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	p.printf("%s%s %s\n", name, labels, formatFloat(value))
+}
+
+func label(name, value string) string {
+	return name + "=" + strconv.Quote(value)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
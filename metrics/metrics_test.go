@@ -0,0 +1,218 @@
+package metrics_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lanz-dev/go-mygrate/metrics"
+	"github.com/lanz-dev/go-mygrate/mygrate"
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+var errUnitTest = errors.New("unittest")
+
+func nilFunc() error {
+	return nil
+}
+
+func errFunc() error {
+	return errUnitTest
+}
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	m := mygrate.New(mygrate.WithStore(store.NewMemoryStore()))
+	m.Register("1", nilFunc, nilFunc)
+	m.Register("2", nilFunc, nilFunc)
+	m.Register("3", errFunc, nilFunc)
+	c := metrics.New(m, metrics.WithBuckets([]float64{1, 0.5}))
+
+	if _, err := m.Migrate(false); !errors.Is(err, errUnitTest) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, errUnitTest, err)
+	}
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf(`expected the Prometheus content type, got '%s'`, ct)
+	}
+
+	for _, line := range []string{
+		"# TYPE mygrate_migrations_applied gauge",
+		"mygrate_migrations_applied 2",
+		"mygrate_migrations_pending 1",
+		"mygrate_last_run_success 0",
+		`mygrate_migration_failures_total{direction="up"} 1`,
+		`mygrate_migration_failures_total{direction="down"} 0`,
+		"# TYPE mygrate_migration_duration_seconds histogram",
+		`mygrate_migration_duration_seconds_bucket{direction="up",le="0.5"} 2`,
+		`mygrate_migration_duration_seconds_bucket{direction="up",le="1"} 2`,
+		`mygrate_migration_duration_seconds_bucket{direction="up",le="+Inf"} 2`,
+		`mygrate_migration_duration_seconds_count{direction="up"} 2`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Fatalf(`expected line '%s', got '%s'`, line, body)
+		}
+	}
+	if !strings.Contains(string(body), "mygrate_last_run_timestamp_seconds ") ||
+		!strings.Contains(string(body), "mygrate_lock_wait_seconds ") {
+		t.Fatalf(`expected the last run and the lock wait, got '%s'`, body)
+	}
+}
+
+func TestCollector_BeforeFirstRun(t *testing.T) {
+	t.Parallel()
+
+	m := mygrate.New(mygrate.WithStore(store.NewMemoryStore()))
+	m.Register("1", nilFunc, nilFunc)
+	c := metrics.New(m)
+
+	var sb strings.Builder
+	if _, err := c.WriteTo(&sb); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if strings.Contains(sb.String(), "mygrate_last_run") {
+		t.Fatalf(`expected no run metrics before the first run, got '%s'`, sb.String())
+	}
+	if !strings.Contains(sb.String(), "mygrate_migrations_applied 0\n") ||
+		!strings.Contains(sb.String(), "mygrate_migrations_pending 1\n") {
+		t.Fatalf(`expected the status before the first run, got '%s'`, sb.String())
+	}
+}
+
+func TestCollector_StatusAtScrape(t *testing.T) {
+	t.Parallel()
+
+	// another instance migrates the shared store
+	mem := store.NewMemoryStore()
+	other := mygrate.New(mygrate.WithStore(mem))
+	other.Register("1", nilFunc, nilFunc)
+	other.Register("2", nilFunc, nilFunc)
+	if _, err := other.Steps(1); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	m := mygrate.New(mygrate.WithStore(mem))
+	m.Register("1", nilFunc, nilFunc)
+	m.Register("2", nilFunc, nilFunc)
+	c := metrics.New(m)
+
+	var sb strings.Builder
+	if _, err := c.WriteTo(&sb); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if !strings.Contains(sb.String(), "mygrate_migrations_applied 1\n") ||
+		!strings.Contains(sb.String(), "mygrate_migrations_pending 1\n") {
+		t.Fatalf(`expected the status of the store, got '%s'`, sb.String())
+	}
+
+	if _, err := other.Migrate(false); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	sb.Reset()
+	if _, err := c.WriteTo(&sb); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if !strings.Contains(sb.String(), "mygrate_migrations_applied 2\n") ||
+		!strings.Contains(sb.String(), "mygrate_migrations_pending 0\n") {
+		t.Fatalf(`expected the updated status of the store, got '%s'`, sb.String())
+	}
+}
+
+func TestCollector_ScrapeDuringRun(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]func(t *testing.T) mygrate.Store{
+		"memory": func(t *testing.T) mygrate.Store {
+			return store.NewMemoryStore()
+		},
+		"file": func(t *testing.T) mygrate.Store {
+			return store.NewFileStoreWithPath(filepath.Join(t.TempDir(), "state.json"), store.WithHistory())
+		},
+	}
+
+	for name, newStore := range tests {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// each migration waits for a scrape, so the scrapes overlap the whole run
+			scrapes := make(chan struct{}, 1)
+			waitScrape := func() error {
+				<-scrapes
+				return nil
+			}
+
+			m := mygrate.New(mygrate.WithStore(newStore(t)))
+			for i := 0; i < 50; i++ {
+				m.Register(fmt.Sprintf("%03d", i), waitScrape, nilFunc)
+			}
+			c := metrics.New(m)
+
+			done := make(chan struct{})
+			scraped := make(chan struct{})
+			go func() {
+				defer close(scraped)
+				for {
+					select {
+					case <-done:
+						return
+					default:
+						_, _ = c.WriteTo(io.Discard)
+					}
+					select {
+					case scrapes <- struct{}{}:
+					default:
+					}
+				}
+			}()
+
+			_, err := m.Migrate(false)
+			close(done)
+			<-scraped
+			if err != nil {
+				t.Fatalf(`did not expected err '%s'`, err)
+			}
+
+			var sb strings.Builder
+			if _, err := c.WriteTo(&sb); err != nil {
+				t.Fatalf(`did not expected err '%s'`, err)
+			}
+			if !strings.Contains(sb.String(), "mygrate_migrations_applied 50\n") {
+				t.Fatalf(`expected all migrations to be applied, got '%s'`, sb.String())
+			}
+		})
+	}
+}
+
+func TestCollector_ScrapeDoesNotInit(t *testing.T) {
+	t.Parallel()
+
+	mock := &store.MockStore{
+		InitFunc:     func() error { return nil },
+		FindDoneFunc: func() ([]string, error) { return []string{"1"}, nil },
+	}
+	m := mygrate.New(mygrate.WithStore(mock))
+	m.Register("1", nilFunc, nilFunc)
+	c := metrics.New(m)
+
+	var sb strings.Builder
+	if _, err := c.WriteTo(&sb); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if mock.InitCalled {
+		t.Fatal(`expected a scrape not to initialize the store`)
+	}
+	if !strings.Contains(sb.String(), "mygrate_migrations_applied 1\n") {
+		t.Fatalf(`expected the status of the store, got '%s'`, sb.String())
+	}
+}
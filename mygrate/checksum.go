@@ -0,0 +1,122 @@
+package mygrate
+
+import (
+	"context"
+)
+
+func (s *Service) storeSetChecksum(ctx context.Context, myg mygration) error {
+	cs, ok := s.store.(ChecksumStore)
+	if !ok || myg.Checksum == "" {
+		return nil
+	}
+
+	if err := cs.SetChecksum(ctx, myg.ID, myg.Checksum); err != nil {
+		return errStore(myg.ID, err)
+	}
+	return nil
+}
+
+// setChecksumAfterUp records the checksum of myg after its up func, unless the
+// store has already recorded it together with the Record of the execution.
+func (s *Service) setChecksumAfterUp(ctx context.Context, myg mygration) error {
+	recorded := false
+	if myg.UpTx != nil {
+		_, recorded = s.store.(TxRecordStore)
+	} else {
+		_, recorded = s.store.(RecordStore)
+	}
+	if recorded {
+		return nil
+	}
+	return s.storeSetChecksum(ctx, myg)
+}
+
+// findChecksums returns the recorded checksums or nil if the store does not implement ChecksumStore.
+func (s *Service) findChecksums(ctx context.Context) (map[string]string, error) {
+	cs, ok := s.store.(ChecksumStore)
+	if !ok {
+		return nil, nil
+	}
+
+	sums, err := cs.FindChecksums(ctx)
+	if err != nil {
+		return nil, errStore("", err)
+	}
+	return sums, nil
+}
+
+// drifted reports if the recorded checksum of an applied migration differs from the registered one.
+// Migrations without a checksum or without a recorded checksum never drift.
+func drifted(myg mygration, sums map[string]string) bool {
+	recorded := sums[myg.ID]
+	return myg.Checksum != "" && recorded != "" && recorded != myg.Checksum
+}
+
+// verifyChecksums returns an error for the first applied migration whose checksum has changed.
+func (s *Service) verifyChecksums(ctx context.Context) error {
+	sums, err := s.findChecksums(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, myg := range s.migrations {
+		if drifted(myg, sums) {
+			return errChecksum(myg.ID, sums[myg.ID], myg.Checksum)
+		}
+	}
+	return nil
+}
+
+// Repair records the registered checksums of all applied migrations, which
+// accepts changed migrations and adds missing checksums, e.g. of migrations
+// applied before they had a checksum. It returns the number of updated checksums.
+func (s *Service) Repair() (int, error) {
+	return s.RepairContext(context.Background())
+}
+
+// RepairContext is like Repair, but passes ctx to the store.
+func (s *Service) RepairContext(ctx context.Context) (int, error) {
+	if err := s.init(ctx); err != nil {
+		return 0, err
+	}
+
+	if _, ok := s.store.(ChecksumStore); !ok {
+		return 0, errStore("", ErrChecksumUnsupported)
+	}
+
+	unlock, err := s.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	doneIDs, err := s.storeFindDone(ctx)
+	if err != nil {
+		return 0, errStore("", err)
+	}
+	applied := make(map[string]bool, len(doneIDs))
+	for _, id := range doneIDs {
+		applied[id] = true
+	}
+
+	sums, err := s.findChecksums(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, myg := range s.migrations {
+		if !applied[myg.ID] || myg.Checksum == "" || sums[myg.ID] == myg.Checksum {
+			continue
+		}
+		if err := s.storeSetChecksum(ctx, myg); err != nil {
+			return n, err
+		}
+		if err := s.appendRepair(ctx, myg, sums[myg.ID]); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
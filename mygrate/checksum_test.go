@@ -0,0 +1,144 @@
+package mygrate_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lanz-dev/go-mygrate/mygrate"
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+func nilContextFunc(context.Context) error {
+	return nil
+}
+
+func TestService_Checksum(t *testing.T) {
+	t.Parallel()
+
+	mem := store.NewMemoryStore()
+	m := mygrate.New(mygrate.WithStore(mem))
+	m.RegisterContext("1", nilContextFunc, nilContextFunc, mygrate.WithChecksum("v1"))
+	m.RegisterContext("2", nilContextFunc, nilContextFunc)
+
+	if _, err := m.Migrate(false); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	sums, err := mem.FindChecksums(context.Background())
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if len(sums) != 1 || sums["1"] != "v1" {
+		t.Fatalf(`expected checksum 'v1' for '1', got '%v'`, sums)
+	}
+
+	// the applied migration was changed afterwards
+	m = mygrate.New(mygrate.WithStore(mem))
+	m.RegisterContext("1", nilContextFunc, nilContextFunc, mygrate.WithChecksum("v2"))
+	m.RegisterContext("2", nilContextFunc, nilContextFunc)
+	m.RegisterContext("3", nilContextFunc, nilContextFunc)
+
+	_, err = m.Migrate(false)
+	if !errors.Is(err, mygrate.ErrChecksumMismatch) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, mygrate.ErrChecksumMismatch, err)
+	}
+	var merr *mygrate.Error
+	if !errors.As(err, &merr) || merr.ID != "1" {
+		t.Fatalf(`expected err for id '1', got '%v'`, err)
+	}
+
+	report, err := m.Status()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	drifted := report.Drifted()
+	if len(drifted) != 1 || drifted[0].ID != "1" {
+		t.Fatalf(`expected '1' to be drifted, got '%v'`, drifted)
+	}
+
+	n, err := m.Repair()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if n != 1 {
+		t.Fatalf(`expected '%d' repaired checksums, got '%d'`, 1, n)
+	}
+
+	n, err = m.Migrate(false)
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if n != 1 {
+		t.Fatalf(`expected '%d' migrations, got '%d'`, 1, n)
+	}
+}
+
+func TestService_Checksum_NotRecorded(t *testing.T) {
+	t.Parallel()
+
+	// applied before the migration had a checksum
+	mem := store.NewMemoryStore()
+	if err := mem.Up("1", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	m := mygrate.New(mygrate.WithStore(mem))
+	m.RegisterContext("1", nilContextFunc, nilContextFunc, mygrate.WithChecksum("v1"))
+
+	if _, err := m.Migrate(false); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	n, err := m.Repair()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if n != 1 {
+		t.Fatalf(`expected '%d' repaired checksums, got '%d'`, 1, n)
+	}
+}
+
+func TestService_Repair_Unsupported(t *testing.T) {
+	t.Parallel()
+
+	m := mygrate.New(mygrate.WithStore(buildMock()))
+
+	_, err := m.Repair()
+	if !errors.Is(err, mygrate.ErrChecksumUnsupported) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, mygrate.ErrChecksumUnsupported, err)
+	}
+}
+
+// noSetChecksumStore fails if the checksum is recorded apart from the Record.
+type noSetChecksumStore struct {
+	*store.MemoryStore
+}
+
+func (noSetChecksumStore) SetChecksum(context.Context, string, string) error {
+	return errUnitTest
+}
+
+func TestService_Checksum_RecordedWithUp(t *testing.T) {
+	t.Parallel()
+
+	mem := store.NewMemoryStore()
+	m := mygrate.New(mygrate.WithStore(noSetChecksumStore{mem}))
+	m.RegisterContext("1", nilContextFunc, nilContextFunc, mygrate.WithChecksum("v1"))
+
+	if _, err := m.Migrate(false); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	records, err := mem.FindRecords(context.Background())
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if records["1"].Checksum != "v1" {
+		t.Fatalf(`expected the record to carry checksum 'v1', got '%+v'`, records["1"])
+	}
+	history, _ := m.History()
+	if len(history) != 1 || history[0].Event != store.EventUp || history[0].Checksum != "v1" {
+		t.Fatalf(`expected an up event with checksum 'v1', got '%+v'`, history)
+	}
+}
@@ -2,6 +2,9 @@
 
 import (
 	"errors"
+	"fmt"
+
+	"github.com/lanz-dev/go-mygrate/store"
 )
 
 var (
@@ -13,6 +16,28 @@
 	ErrStore = errors.New("store returned an error")
 	// ErrUpFn will be returned if the up func will return an error.
 	ErrUpFn = errors.New("migrations up returned an error")
+	// ErrTxUnsupported will be returned if a transactional migration runs on a store without TxStore.
+	ErrTxUnsupported = errors.New("store does not support transactions")
+	// ErrUnknownID will be returned if a given ID is not registered.
+	ErrUnknownID = errors.New("migration id is not registered")
+	// ErrInvalidMigration will be returned if a registered migration is invalid, see Service.Validate.
+	ErrInvalidMigration = errors.New("migration is invalid")
+	// ErrNilFunc will be returned if a migration without an up or down func is executed.
+	ErrNilFunc = errors.New("migration func is nil")
+	// ErrChecksumMismatch will be returned if the checksum of an applied migration has changed.
+	ErrChecksumMismatch = errors.New("checksum of applied migration has changed")
+	// ErrHook will be returned if a hook returned an error.
+	ErrHook = errors.New("hook returned an error")
+	// ErrHistoryUnsupported will be returned by History if the store does not implement HistoryStore.
+	// It is the same error as store.ErrHistoryUnsupported.
+	ErrHistoryUnsupported = store.ErrHistoryUnsupported
+	// ErrChecksumUnsupported will be returned by Repair if the store does not implement ChecksumStore.
+	ErrChecksumUnsupported = errors.New("store does not support checksums")
+	// ErrTimeout will be returned if a migration exceeded its timeout or the deadline of the run.
+	ErrTimeout = errors.New("migration timed out")
+	// ErrStillRunning will be returned if a migration func did not return within the grace period
+	// after its context was canceled, see WithTimeoutGrace.
+	ErrStillRunning = errors.New("migration func is still running")
 )
 
 // Error is a custom mygrate error type.
@@ -52,3 +77,23 @@ func errUp(id string, err error) error {
 func errDown(id string, err error) error {
 	return &Error{ID: id, Err: err, InternalErr: ErrDownFn}
 }
+
+func errHook(id string, err error) error {
+	return &Error{ID: id, Err: err, InternalErr: ErrHook}
+}
+
+func errUnknownID(id string) error {
+	return &Error{ID: id, Err: errors.New(id), InternalErr: ErrUnknownID}
+}
+
+func errInvalid(id string, format string, args ...interface{}) error {
+	return &Error{ID: id, Err: fmt.Errorf(format, args...), InternalErr: ErrInvalidMigration}
+}
+
+func errChecksum(id string, recorded string, registered string) error {
+	return &Error{
+		ID:          id,
+		Err:         fmt.Errorf("%s: recorded %s, registered %s", id, recorded, registered),
+		InternalErr: ErrChecksumMismatch,
+	}
+}
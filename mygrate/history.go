@@ -0,0 +1,85 @@
+package mygrate
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+// appendHistory logs the execution of step to a HistoryStore.
+func (s *Service) appendHistory(ctx context.Context, step PlanStep, rec store.Record, runErr error) error {
+	hs, ok := s.store.(HistoryStore)
+	if !ok {
+		return nil
+	}
+
+	e := store.HistoryEntry{Record: rec, Event: rec.Direction, Reason: step.Reason}
+	if runErr != nil {
+		e.Event = store.EventFailed
+		e.Error = runErr.Error()
+	}
+
+	// the execution may have been interrupted by the canceled run context, but it has to be logged anyway
+	if err := hs.AppendHistory(detach(ctx), e); err != nil && !errors.Is(err, ErrHistoryUnsupported) {
+		return errStore(rec.ID, err)
+	}
+	return nil
+}
+
+// appendRepair logs the accepted checksum of myg to a HistoryStore.
+func (s *Service) appendRepair(ctx context.Context, myg mygration, previous string) error {
+	hs, ok := s.store.(HistoryStore)
+	if !ok {
+		return nil
+	}
+
+	reason := "checksum missing"
+	if previous != "" {
+		reason = "checksum changed from " + previous
+	}
+
+	e := store.HistoryEntry{
+		Record: store.Record{
+			ID:       myg.ID,
+			Executed: time.Now().UTC(),
+			Hostname: s.hostname,
+			PID:      s.pid,
+			Version:  s.version,
+			Checksum: myg.Checksum,
+		},
+		Event:  store.EventRepair,
+		Reason: reason,
+	}
+
+	if err := hs.AppendHistory(ctx, e); err != nil && !errors.Is(err, ErrHistoryUnsupported) {
+		return errStore(myg.ID, err)
+	}
+	return nil
+}
+
+// History returns the history of all executions, oldest entry first.
+// The store has to implement HistoryStore, like all built-in stores do, the
+// SQLStore and the FileStore only with store.WithHistory.
+func (s *Service) History() ([]store.HistoryEntry, error) {
+	return s.HistoryContext(context.Background())
+}
+
+// HistoryContext is like History, but passes ctx to the store.
+func (s *Service) HistoryContext(ctx context.Context) ([]store.HistoryEntry, error) {
+	if err := s.init(ctx); err != nil {
+		return nil, err
+	}
+
+	hs, ok := s.store.(HistoryStore)
+	if !ok {
+		return nil, errStore("", ErrHistoryUnsupported)
+	}
+
+	history, err := hs.FindHistory(ctx)
+	if err != nil {
+		return nil, errStore("", err)
+	}
+	return history, nil
+}
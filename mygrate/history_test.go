@@ -0,0 +1,93 @@
+package mygrate_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/lanz-dev/go-mygrate/mygrate"
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+func TestService_History(t *testing.T) {
+	t.Parallel()
+
+	mem := store.NewMemoryStore()
+	m := mygrate.New(mygrate.WithStore(mem), mygrate.WithVersion("v1"))
+	m.RegisterContext("1", nilContextFunc, nilContextFunc, mygrate.WithChecksum("a"))
+	m.Register("2", nilFunc, errFunc)
+
+	if _, err := m.Migrate(false); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if _, err := m.Redo(); !errors.Is(err, errUnitTest) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, errUnitTest, err)
+	}
+
+	m = mygrate.New(mygrate.WithStore(mem))
+	m.RegisterContext("1", nilContextFunc, nilContextFunc, mygrate.WithChecksum("b"))
+	m.Register("2", nilFunc, nilFunc)
+	if _, err := m.Repair(); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	history, err := m.History()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	expected := []struct {
+		id, event, reason string
+	}{
+		{"1", store.EventUp, "pending"},
+		{"2", store.EventUp, "pending"},
+		{"2", store.EventFailed, "redo"},
+		{"1", store.EventRepair, "checksum changed from a"},
+	}
+	if len(history) != len(expected) {
+		t.Fatalf(`expected '%d' entries, got '%+v'`, len(expected), history)
+	}
+	for i, e := range expected {
+		h := history[i]
+		if h.ID != e.id || h.Event != e.event || h.Reason != e.reason {
+			t.Fatalf(`expected entry '%d' to be '%v', got '%+v'`, i, e, h)
+		}
+	}
+
+	if history[0].Checksum != "a" || history[0].Version != "v1" {
+		t.Fatalf(`expected checksum 'a' and version 'v1', got '%+v'`, history[0])
+	}
+	if history[2].Direction != store.DirectionDown || history[2].Error == "" {
+		t.Fatalf(`expected a failed down with an error, got '%+v'`, history[2])
+	}
+}
+
+func TestService_History_Unsupported(t *testing.T) {
+	t.Parallel()
+
+	m := mygrate.New(mygrate.WithStore(buildMock()))
+
+	_, err := m.HistoryContext(context.Background())
+	if !errors.Is(err, mygrate.ErrHistoryUnsupported) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, mygrate.ErrHistoryUnsupported, err)
+	}
+}
+
+func TestService_History_Disabled(t *testing.T) {
+	t.Parallel()
+
+	m := mygrate.New(mygrate.WithStore(store.NewFileStoreWithPath(filepath.Join(t.TempDir(), "state.json"))))
+	m.Register("1", nilFunc, nilFunc)
+	m.Register("2", errFunc, nilFunc)
+
+	// the failure is not logged to the history, but the run fails with it
+	if _, err := m.Migrate(false); !errors.Is(err, errUnitTest) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, errUnitTest, err)
+	}
+
+	_, err := m.History()
+	if !errors.Is(err, mygrate.ErrHistoryUnsupported) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, mygrate.ErrHistoryUnsupported, err)
+	}
+}
@@ -0,0 +1,89 @@
+package mygrate
+
+import (
+	"context"
+)
+
+// RunHook is called before or after all migrations of a run, see WithBeforeAll and WithAfterAll.
+// Returning an error aborts the run.
+type RunHook func(ctx context.Context, plan Plan) error
+
+// MigrationHook is called before or after a single migration, see WithBeforeEach and WithAfterEach.
+// Returning an error aborts the run.
+type MigrationHook func(ctx context.Context, id string, dir Direction) error
+
+// ErrorHook is called if a run fails, see WithOnError.
+// id and dir are empty if the error did not belong to a migration, e.g. of a RunHook.
+type ErrorHook func(ctx context.Context, id string, dir Direction, err error)
+
+type hooks struct {
+	beforeAll  []RunHook
+	afterAll   []RunHook
+	beforeEach []MigrationHook
+	afterEach  []MigrationHook
+	onError    []ErrorHook
+}
+
+// WithBeforeAll adds a hook, which is called after the store is locked and
+// before the first migration of a run, e.g. to take a backup.
+// It is not called if there is nothing to do.
+func WithBeforeAll(hook RunHook) Option {
+	return func(s *Service) {
+		s.hooks.beforeAll = append(s.hooks.beforeAll, hook)
+	}
+}
+
+// WithAfterAll adds a hook, which is called after all migrations of a run
+// succeeded, e.g. to flush caches. It is not called if there is nothing to do.
+func WithAfterAll(hook RunHook) Option {
+	return func(s *Service) {
+		s.hooks.afterAll = append(s.hooks.afterAll, hook)
+	}
+}
+
+// WithBeforeEach adds a hook, which is called before the up or down func of each migration.
+func WithBeforeEach(hook MigrationHook) Option {
+	return func(s *Service) {
+		s.hooks.beforeEach = append(s.hooks.beforeEach, hook)
+	}
+}
+
+// WithAfterEach adds a hook, which is called after each migration and its bookkeeping succeeded.
+func WithAfterEach(hook MigrationHook) Option {
+	return func(s *Service) {
+		s.hooks.afterEach = append(s.hooks.afterEach, hook)
+	}
+}
+
+// WithOnError adds a hook, which is called with the error of a failed run,
+// including the errors of the lock, the planning, e.g. a checksum mismatch,
+// and the other hooks.
+func WithOnError(hook ErrorHook) Option {
+	return func(s *Service) {
+		s.hooks.onError = append(s.hooks.onError, hook)
+	}
+}
+
+func (h hooks) runAll(ctx context.Context, all []RunHook, plan Plan) error {
+	for _, hook := range all {
+		if err := hook(ctx, plan); err != nil {
+			return errHook("", err)
+		}
+	}
+	return nil
+}
+
+func (h hooks) runEach(ctx context.Context, each []MigrationHook, step PlanStep) error {
+	for _, hook := range each {
+		if err := hook(ctx, step.ID, step.Direction); err != nil {
+			return errHook(step.ID, err)
+		}
+	}
+	return nil
+}
+
+func (h hooks) runError(ctx context.Context, id string, dir Direction, err error) {
+	for _, hook := range h.onError {
+		hook(ctx, id, dir, err)
+	}
+}
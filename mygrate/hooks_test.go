@@ -0,0 +1,194 @@
+package mygrate_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lanz-dev/go-mygrate/mygrate"
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+// hookRecorder records the calls of all hooks.
+type hookRecorder struct {
+	calls []string
+}
+
+func (r *hookRecorder) options(failOn string) []mygrate.Option {
+	each := func(name string) mygrate.MigrationHook {
+		return func(ctx context.Context, id string, dir mygrate.Direction) error {
+			call := name + " " + string(dir) + " " + id
+			r.calls = append(r.calls, call)
+			if call == failOn {
+				return errUnitTest
+			}
+			return nil
+		}
+	}
+	all := func(name string) mygrate.RunHook {
+		return func(ctx context.Context, plan mygrate.Plan) error {
+			r.calls = append(r.calls, name)
+			if name == failOn {
+				return errUnitTest
+			}
+			return nil
+		}
+	}
+
+	return []mygrate.Option{
+		mygrate.WithBeforeAll(all("beforeAll")),
+		mygrate.WithAfterAll(all("afterAll")),
+		mygrate.WithBeforeEach(each("before")),
+		mygrate.WithAfterEach(each("after")),
+		mygrate.WithOnError(func(ctx context.Context, id string, dir mygrate.Direction, err error) {
+			r.calls = append(r.calls, "error "+string(dir)+" "+id)
+		}),
+	}
+}
+
+func TestService_Hooks(t *testing.T) {
+	t.Parallel()
+
+	r := &hookRecorder{}
+	m := mygrate.New(append(r.options(""), mygrate.WithStore(store.NewMemoryStore()))...)
+	m.Register("1", nilFunc, nilFunc)
+	m.Register("2", nilFunc, nilFunc)
+
+	if _, err := m.Migrate(false); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	// nothing to do, so no hooks are called
+	if _, err := m.Migrate(false); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	expected := "beforeAll, before up 1, after up 1, before up 2, after up 2, afterAll"
+	if calls := strings.Join(r.calls, ", "); calls != expected {
+		t.Fatalf(`expected calls '%s', got '%s'`, expected, calls)
+	}
+}
+
+func TestService_Hooks_Abort(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		failOn   string
+		expected string
+		id       string
+	}{
+		"before all": {
+			failOn:   "beforeAll",
+			expected: "beforeAll, error  ",
+		},
+		"before each": {
+			failOn:   "before up 2",
+			expected: "beforeAll, before up 1, after up 1, before up 2, error up 2",
+			id:       "2",
+		},
+		"after all": {
+			failOn:   "afterAll",
+			expected: "beforeAll, before up 1, after up 1, before up 2, after up 2, afterAll, error  ",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &hookRecorder{}
+			m := mygrate.New(append(r.options(tt.failOn), mygrate.WithStore(store.NewMemoryStore()))...)
+			m.Register("1", nilFunc, nilFunc)
+			m.Register("2", nilFunc, nilFunc)
+
+			_, err := m.Migrate(false)
+
+			if !errors.Is(err, mygrate.ErrHook) || !errors.Is(err, errUnitTest) {
+				t.Fatalf(`expected err to be '%s' and '%s', got '%v'`, mygrate.ErrHook, errUnitTest, err)
+			}
+			var merr *mygrate.Error
+			if !errors.As(err, &merr) || merr.ID != tt.id {
+				t.Fatalf(`expected err for id '%s', got '%v'`, tt.id, err)
+			}
+			if calls := strings.Join(r.calls, ", "); calls != tt.expected {
+				t.Fatalf(`expected calls '%s', got '%s'`, tt.expected, calls)
+			}
+		})
+	}
+}
+
+func TestService_Hooks_OnMigrationError(t *testing.T) {
+	t.Parallel()
+
+	r := &hookRecorder{}
+	m := mygrate.New(append(r.options(""), mygrate.WithStore(store.NewMemoryStore()))...)
+	m.Register("1", errFunc, nilFunc)
+
+	if _, err := m.Migrate(false); !errors.Is(err, mygrate.ErrUpFn) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, mygrate.ErrUpFn, err)
+	}
+
+	expected := "beforeAll, before up 1, error up 1"
+	if calls := strings.Join(r.calls, ", "); calls != expected {
+		t.Fatalf(`expected calls '%s', got '%s'`, expected, calls)
+	}
+}
+
+func TestService_Hooks_OnRunError(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		setup    func(opts []mygrate.Option) *mygrate.Service
+		err      error
+		expected string
+	}{
+		"lock": {
+			setup: func(opts []mygrate.Option) *mygrate.Service {
+				mock := &store.MockStore{}
+				mock.LockFunc = func() error {
+					return errUnitTest
+				}
+				return mygrate.New(append(opts, mygrate.WithStore(mock))...)
+			},
+			err:      mygrate.ErrStore,
+			expected: "error  ",
+		},
+		"checksum mismatch": {
+			setup: func(opts []mygrate.Option) *mygrate.Service {
+				mem := store.NewMemoryStore()
+				m := mygrate.New(mygrate.WithStore(mem))
+				m.RegisterContext("1", nilContextFunc, nilContextFunc, mygrate.WithChecksum("v1"))
+				if _, err := m.Migrate(false); err != nil {
+					t.Fatalf(`did not expected err '%s'`, err)
+				}
+				m = mygrate.New(append(opts, mygrate.WithStore(mem))...)
+				m.RegisterContext("1", nilContextFunc, nilContextFunc, mygrate.WithChecksum("v2"))
+				return m
+			},
+			err:      mygrate.ErrChecksumMismatch,
+			expected: "error  1",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &hookRecorder{}
+			logger := &logRecorder{}
+			m := tt.setup(append(r.options(""), mygrate.WithLogger(logger)))
+
+			if _, err := m.Migrate(false); !errors.Is(err, tt.err) {
+				t.Fatalf(`expected err to be '%s', got '%v'`, tt.err, err)
+			}
+			if calls := strings.Join(r.calls, ", "); calls != tt.expected {
+				t.Fatalf(`expected calls '%s', got '%s'`, tt.expected, calls)
+			}
+			if msgs := strings.Join(logger.msgs, ", "); !strings.Contains(msgs, "error run failed") {
+				t.Fatalf(`expected 'error run failed' to be logged, got '%s'`, msgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package mygrate
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Level is the verbosity of a log message.
+type Level int
+
+const (
+	// LevelError logs failed runs.
+	LevelError Level = iota
+	// LevelInfo logs runs, finished migrations and redo decisions.
+	LevelInfo
+	// LevelDebug logs also init, locking and started migrations.
+	LevelDebug
+)
+
+// String returns the name of the level.
+func (l Level) String() string {
+	switch l {
+	case LevelError:
+		return "error"
+	case LevelInfo:
+		return "info"
+	case LevelDebug:
+		return "debug"
+	default:
+		return "level(" + strconv.Itoa(int(l)) + ")"
+	}
+}
+
+// Logger receives the log messages of a Service.
+// keyvals are alternating keys and values, e.g. "id", "0001_init", "direction", DirectionUp.
+type Logger interface {
+	Log(level Level, msg string, keyvals ...interface{})
+}
+
+// WithLogger sets a Logger, e.g. NewStdLogger(log.Default()). Default is no logging.
+func WithLogger(logger Logger) Option {
+	return func(s *Service) {
+		s.logger = logger
+	}
+}
+
+// WithLogLevel sets the verbosity of the Logger, default is LevelInfo.
+func WithLogLevel(level Level) Option {
+	return func(s *Service) {
+		s.logLevel = level
+	}
+}
+
+func (s *Service) log(level Level, msg string, keyvals ...interface{}) {
+	if s.logger == nil || level > s.logLevel {
+		return
+	}
+	s.logger.Log(level, msg, keyvals...)
+}
+
+type stdLogger struct {
+	logger *log.Logger
+}
+
+// NewStdLogger adapts a *log.Logger to Logger. A message is written as a single
+// line of key=value pairs, e.g. `level=info msg="migration finished" id=1 direction=up duration=2ms`.
+func NewStdLogger(logger *log.Logger) Logger {
+	return stdLogger{logger: logger}
+}
+
+// Log implements Logger.
+func (l stdLogger) Log(level Level, msg string, keyvals ...interface{}) {
+	var sb strings.Builder
+	sb.WriteString("level=")
+	sb.WriteString(level.String())
+	sb.WriteString(" msg=")
+	sb.WriteString(formatValue(msg))
+
+	for i := 0; i < len(keyvals); i += 2 {
+		var v interface{} = "(missing)"
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		fmt.Fprintf(&sb, " %s=%s", keyvals[i], formatValue(v))
+	}
+
+	l.logger.Print(sb.String())
+}
+
+// formatValue quotes values which contain spaces, quotes or '='.
+func formatValue(v interface{}) string {
+	s := fmt.Sprint(v)
+	if s == "" || strings.ContainsAny(s, " \"=\t\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
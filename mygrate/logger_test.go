@@ -0,0 +1,114 @@
+package mygrate_test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/lanz-dev/go-mygrate/mygrate"
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+// logRecorder records the messages of a Logger.
+type logRecorder struct {
+	msgs []string
+}
+
+func (l *logRecorder) Log(level mygrate.Level, msg string, keyvals ...interface{}) {
+	l.msgs = append(l.msgs, level.String()+" "+msg)
+}
+
+func TestService_WithLogger(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		level    mygrate.Level
+		expected []string
+	}{
+		"info": {
+			level: mygrate.LevelInfo,
+			expected: []string{
+				"info run started",
+				"info migration finished",
+				"error run failed",
+			},
+		},
+		"debug": {
+			level: mygrate.LevelDebug,
+			expected: []string{
+				"debug store initialized",
+				"debug acquiring lock",
+				"debug lock acquired",
+				"info run started",
+				"debug migration started",
+				"info migration finished",
+				"debug migration started",
+				"error run failed",
+				"debug lock released",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			logger := &logRecorder{}
+			m := mygrate.New(
+				mygrate.WithStore(store.NewMemoryStore()),
+				mygrate.WithLogger(logger),
+				mygrate.WithLogLevel(tt.level),
+			)
+			m.Register("1", nilFunc, nilFunc)
+			m.Register("2", errFunc, nilFunc)
+
+			if _, err := m.Migrate(false); !errors.Is(err, errUnitTest) {
+				t.Fatalf(`expected err to be '%s', got '%v'`, errUnitTest, err)
+			}
+
+			expected := strings.Join(tt.expected, "\n")
+			if msgs := strings.Join(logger.msgs, "\n"); msgs != expected {
+				t.Fatalf(`expected messages '%s', got '%s'`, expected, msgs)
+			}
+		})
+	}
+}
+
+func TestService_Logger_UnlockFailed(t *testing.T) {
+	t.Parallel()
+
+	logger := &logRecorder{}
+	mock := &store.MockStore{
+		InitFunc:     func() error { return nil },
+		FindDoneFunc: func() ([]string, error) { return nil, nil },
+		LockFunc:     func() error { return nil },
+		UnlockFunc:   func() error { return store.ErrLockLost },
+	}
+	m := mygrate.New(mygrate.WithStore(mock), mygrate.WithLogger(logger))
+
+	if _, err := m.Migrate(false); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if msgs := strings.Join(logger.msgs, "\n"); !strings.Contains(msgs, "error unlock failed") {
+		t.Fatalf(`expected 'error unlock failed' to be logged, got '%s'`, msgs)
+	}
+}
+
+func TestNewStdLogger(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger := mygrate.NewStdLogger(log.New(&buf, "", 0))
+
+	logger.Log(mygrate.LevelInfo, "migration finished", "id", "1", "direction", mygrate.DirectionUp, "reason", "redo last")
+	logger.Log(mygrate.LevelError, "odd", "key")
+
+	expected := "level=info msg=\"migration finished\" id=1 direction=up reason=\"redo last\"\n" +
+		"level=error msg=odd key=(missing)\n"
+	if buf.String() != expected {
+		t.Fatalf(`expected output '%s', got '%s'`, expected, buf.String())
+	}
+}
@@ -2,7 +2,11 @@
 package mygrate
 
 import (
+	"context"
+	"database/sql"
 	"time"
+
+	"github.com/lanz-dev/go-mygrate/store"
 )
 
 // Registerer provides methods to register a migration.
@@ -13,6 +17,18 @@ type Registerer interface {
 	Register(string, func() error, func() error)
 }
 
+// ContextRegisterer provides methods to register a context aware migration.
+type ContextRegisterer interface {
+	// RegisterContext registers a migration whose funcs receive the context of the run.
+	RegisterContext(string, func(context.Context) error, func(context.Context) error, ...MigrationOption)
+}
+
+// TxRegisterer provides methods to register a migration which runs inside a database transaction.
+type TxRegisterer interface {
+	// RegisterTx registers a migration whose funcs receive the transaction of the run.
+	RegisterTx(string, func(context.Context, *sql.Tx) error, func(context.Context, *sql.Tx) error, ...MigrationOption)
+}
+
 // Locker provides method which lock databases, filesystems, etc.
 type Locker interface {
 	// Lock will be called before migrating.
@@ -21,6 +37,15 @@ type Locker interface {
 	Unlock() error
 }
 
+// ContextLocker is the context aware variant of Locker.
+// It will be preferred over Locker if a store implements both.
+type ContextLocker interface {
+	// LockContext will be called before migrating.
+	LockContext(ctx context.Context) error
+	// UnlockContext will be called after migrating.
+	UnlockContext(ctx context.Context) error
+}
+
 // Store provides method to save the current state of migrations.
 type Store interface {
 	// Init should prepare the store for usage.
@@ -35,8 +60,113 @@ type Store interface {
 	FindDone() ([]string, error)
 }
 
+// ContextStore is the context aware variant of Store.
+// It will be preferred over Store if a store implements both.
+type ContextStore interface {
+	// InitContext should prepare the store for usage.
+	InitContext(ctx context.Context) error
+
+	// UpContext will be called after the migrations up func was run.
+	UpContext(ctx context.Context, id string, executed time.Time) error
+	// DownContext will be called after the migrations down func was run.
+	DownContext(ctx context.Context, id string, executed time.Time) error
+
+	// FindDoneContext returns the IDs from already ran migrations.
+	FindDoneContext(ctx context.Context) ([]string, error)
+}
+
+// ExecutedFinder is an optional extension of Store, which also knows
+// when the already ran migrations were executed.
+type ExecutedFinder interface {
+	// FindExecuted returns the execution time of already ran migrations by ID.
+	FindExecuted(ctx context.Context) (map[string]time.Time, error)
+}
+
+// TxStore is an optional extension of Store, which allows to execute
+// migrations registered with RegisterTx and their bookkeeping in the same transaction.
+type TxStore interface {
+	// BeginTx starts the transaction for a single migration.
+	BeginTx(ctx context.Context) (*sql.Tx, error)
+
+	// UpTx will be called after the migrations up func was run inside tx.
+	UpTx(ctx context.Context, tx *sql.Tx, id string, executed time.Time) error
+	// DownTx will be called after the migrations down func was run inside tx.
+	DownTx(ctx context.Context, tx *sql.Tx, id string, executed time.Time) error
+}
+
+// RecordStore is an optional extension of Store, which persists the metadata
+// of each execution like its duration, host and application version.
+// It will be preferred over Up and Down of Store and ContextStore.
+// UpRecord also records the checksum of the Record, so a ChecksumStore's
+// SetChecksum is not called after the up func.
+type RecordStore interface {
+	// UpRecord will be called after the migrations up func was run.
+	UpRecord(ctx context.Context, rec store.Record) error
+	// DownRecord will be called after the migrations down func was run.
+	DownRecord(ctx context.Context, rec store.Record) error
+
+	// FindRecords returns the records of already ran migrations by ID.
+	FindRecords(ctx context.Context) (map[string]store.Record, error)
+}
+
+// TxRecordStore is an optional extension of TxStore, which persists the
+// metadata of transactional migrations. It will be preferred over UpTx and DownTx.
+// Like UpRecord, UpRecordTx also records the checksum in the same transaction.
+type TxRecordStore interface {
+	// UpRecordTx will be called after the migrations up func was run inside tx.
+	UpRecordTx(ctx context.Context, tx *sql.Tx, rec store.Record) error
+	// DownRecordTx will be called after the migrations down func was run inside tx.
+	DownRecordTx(ctx context.Context, tx *sql.Tx, rec store.Record) error
+}
+
+// HistoryStore is an optional extension of Store, which keeps an append-only
+// history of all executions, including failed ones and repairs. A store which
+// has the history disabled returns ErrHistoryUnsupported.
+type HistoryStore interface {
+	// AppendHistory will be called after each execution of an up or down func.
+	AppendHistory(ctx context.Context, e store.HistoryEntry) error
+	// FindHistory returns the history, oldest entry first.
+	FindHistory(ctx context.Context) ([]store.HistoryEntry, error)
+}
+
+// RecoveringStore is an optional extension of Store, which repairs a corrupt
+// state on Init, e.g. the FileStore restores its backup.
+type RecoveringStore interface {
+	// Recovered returns an error describing the repaired state, nil if the state was intact.
+	Recovered() error
+}
+
+// ChecksumStore is an optional extension of Store, which records the
+// checksums of migrations registered with WithChecksum.
+type ChecksumStore interface {
+	// SetChecksum records the checksum of an applied migration. It is called by
+	// Repair and after an up func, if the store does not implement RecordStore.
+	SetChecksum(ctx context.Context, id string, checksum string) error
+	// FindChecksums returns the recorded checksums of applied migrations by ID.
+	FindChecksums(ctx context.Context) (map[string]string, error)
+}
+
 type mygration struct {
 	ID   string
-	Up   func() error
-	Down func() error
+	Up   func(context.Context) error
+	Down func(context.Context) error
+
+	// Checksum of the migration's content, see WithChecksum.
+	Checksum string
+	// Timeout of the up or down func, see WithTimeout.
+	Timeout time.Duration
+
+	// UpTx and DownTx are set instead of Up and Down for transactional migrations.
+	UpTx   func(context.Context, *sql.Tx) error
+	DownTx func(context.Context, *sql.Tx) error
+}
+
+// withoutContext adapts a legacy migration func to the context aware signature.
+func withoutContext(fn func() error) func(context.Context) error {
+	if fn == nil {
+		return nil
+	}
+	return func(context.Context) error {
+		return fn()
+	}
 }
@@ -0,0 +1,85 @@
+package mygrate
+
+import (
+	"context"
+	"time"
+)
+
+// EventType is the type of an Event.
+type EventType string
+
+// The events of a run in the order they are emitted. A run emits LockAcquired
+// before it is planned, so RunStarted is the first event which knows the total.
+const (
+	EventLockAcquired       EventType = "lock_acquired"
+	EventRunStarted         EventType = "run_started"
+	EventMigrationStarted   EventType = "migration_started"
+	EventMigrationSucceeded EventType = "migration_succeeded" // the up or down func succeeded
+	EventStoreUpdated       EventType = "store_updated"       // the store recorded the migration
+	EventMigrationFailed    EventType = "migration_failed"    // the func or its bookkeeping failed
+	EventRunFinished        EventType = "run_finished"
+)
+
+// Event describes the progress of a run.
+type Event struct {
+	Type EventType
+
+	// ID, Direction, Reason and Index are set for the migration events.
+	ID        string
+	Direction Direction
+	Reason    string
+	Index     int // Index of the migration in the plan, starting at 0.
+	Total     int // Total number of migrations in the plan, 0 for LockAcquired.
+
+	Time time.Time // Time the event was emitted.
+	// Duration since the migration, the run or, for LockAcquired, the lock attempt started.
+	Duration time.Duration
+	// Err is set for MigrationFailed and for RunFinished of a failed run.
+	Err error
+}
+
+// Observer receives the events of all runs, e.g. to display the progress.
+// It is called synchronously, so it should return quickly.
+type Observer interface {
+	Observe(ctx context.Context, e Event)
+}
+
+// ObserverFunc adapts a func to Observer.
+type ObserverFunc func(ctx context.Context, e Event)
+
+// Observe implements Observer.
+func (f ObserverFunc) Observe(ctx context.Context, e Event) {
+	f(ctx, e)
+}
+
+// WithObserver adds an Observer.
+func WithObserver(observer Observer) Option {
+	return func(s *Service) {
+		s.observers = append(s.observers, observer)
+	}
+}
+
+func (s *Service) emit(ctx context.Context, e Event) {
+	if len(s.observers) == 0 {
+		return
+	}
+
+	e.Time = time.Now()
+	for _, o := range s.observers {
+		o.Observe(ctx, e)
+	}
+}
+
+// stepEvent returns an event for the migration at index of plan, which started at started.
+func stepEvent(typ EventType, plan Plan, index int, started time.Time) Event {
+	step := plan[index]
+	return Event{
+		Type:      typ,
+		ID:        step.ID,
+		Direction: step.Direction,
+		Reason:    step.Reason,
+		Index:     index,
+		Total:     len(plan),
+		Duration:  time.Since(started),
+	}
+}
@@ -0,0 +1,76 @@
+package mygrate_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lanz-dev/go-mygrate/mygrate"
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+func TestService_WithObserver(t *testing.T) {
+	t.Parallel()
+
+	var events []mygrate.Event
+	observer := mygrate.ObserverFunc(func(ctx context.Context, e mygrate.Event) {
+		events = append(events, e)
+	})
+	m := mygrate.New(mygrate.WithStore(store.NewMemoryStore()), mygrate.WithObserver(observer))
+	m.Register("1", nilFunc, nilFunc)
+	m.Register("2", errFunc, nilFunc)
+
+	_, err := m.Migrate(false)
+	if !errors.Is(err, errUnitTest) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, errUnitTest, err)
+	}
+
+	var got []string
+	for _, e := range events {
+		got = append(got, fmt.Sprintf("%s %s %d/%d", e.Type, e.ID, e.Index, e.Total))
+		if e.Time.IsZero() {
+			t.Fatalf(`expected the time of event '%s'`, e.Type)
+		}
+	}
+	expected := []string{
+		"lock_acquired  0/0",
+		"run_started  0/2",
+		"migration_started 1 0/2",
+		"migration_succeeded 1 0/2",
+		"store_updated 1 0/2",
+		"migration_started 2 1/2",
+		"migration_failed 2 1/2",
+		"run_finished  0/2",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf(`expected events '%v', got '%v'`, expected, got)
+	}
+
+	if events[2].Direction != mygrate.DirectionUp || events[2].Reason != "pending" {
+		t.Fatalf(`expected a pending up migration, got '%+v'`, events[2])
+	}
+	if !errors.Is(events[6].Err, errUnitTest) || !errors.Is(events[7].Err, errUnitTest) {
+		t.Fatalf(`expected the failed events to carry err '%s'`, errUnitTest)
+	}
+}
+
+func TestService_WithObserver_NothingToDo(t *testing.T) {
+	t.Parallel()
+
+	var types []mygrate.EventType
+	observer := mygrate.ObserverFunc(func(ctx context.Context, e mygrate.Event) {
+		types = append(types, e.Type)
+	})
+	m := mygrate.New(mygrate.WithStore(store.NewMemoryStore()), mygrate.WithObserver(observer))
+
+	if _, err := m.Migrate(false); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	expected := []mygrate.EventType{mygrate.EventLockAcquired, mygrate.EventRunStarted, mygrate.EventRunFinished}
+	if fmt.Sprint(types) != fmt.Sprint(expected) {
+		t.Fatalf(`expected events '%v', got '%v'`, expected, types)
+	}
+}
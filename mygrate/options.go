@@ -8,3 +8,23 @@ func WithStore(store Store) Option {
 		s.store = store
 	}
 }
+
+// WithVersion sets the version of the application, which is recorded by a RecordStore.
+func WithVersion(version string) Option {
+	return func(s *Service) {
+		s.version = version
+	}
+}
+
+// MigrationOption configures a single migration on registration.
+type MigrationOption func(m *mygration)
+
+// WithChecksum sets a checksum of the migration's content, e.g. a hash of its
+// SQL or a version which is changed together with the funcs.
+// A ChecksumStore records it on up and Migrate refuses to run if the checksum
+// of an applied migration has changed, see Repair.
+func WithChecksum(checksum string) MigrationOption {
+	return func(m *mygration) {
+		m.Checksum = checksum
+	}
+}
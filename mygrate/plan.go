@@ -0,0 +1,283 @@
+package mygrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Direction describes in which direction a migration will be executed.
+type Direction string
+
+const (
+	// DirectionUp means the up func of a migration will be executed.
+	DirectionUp Direction = "up"
+	// DirectionDown means the down func of a migration will be executed.
+	DirectionDown Direction = "down"
+)
+
+const (
+	reasonPending    = "pending"
+	reasonRedo       = "redo"
+	reasonRedoLast   = "redo last"
+	reasonRollbackTo = "rollback to "
+	reasonReset      = "reset"
+	reasonStepDown   = "step down"
+	reasonRefresh    = "refresh"
+)
+
+// PlanStep is a single migration which would be executed by a run.
+type PlanStep struct {
+	ID        string    `json:"id"`
+	Direction Direction `json:"direction"`
+	Reason    string    `json:"reason"`
+
+	myg mygration
+}
+
+// String returns a single line representation of the step.
+func (p PlanStep) String() string {
+	return fmt.Sprintf("%-4s %s (%s)", p.Direction, p.ID, p.Reason)
+}
+
+// Plan is the ordered list of migrations a run would execute.
+type Plan []PlanStep
+
+// String returns a printable representation of the plan, one step per line.
+func (p Plan) String() string {
+	if len(p) == 0 {
+		return "nothing to do\n"
+	}
+
+	var sb strings.Builder
+	for _, step := range p {
+		sb.WriteString(step.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// pending counts the steps which apply a pending migration.
+func (p Plan) pending() int {
+	n := 0
+	for _, step := range p {
+		if step.Reason == reasonPending {
+			n++
+		}
+	}
+	return n
+}
+
+func stepsOf(migrations []mygration, dir Direction, reason string) Plan {
+	plan := make(Plan, 0, len(migrations))
+	for _, myg := range migrations {
+		plan = append(plan, PlanStep{ID: myg.ID, Direction: dir, Reason: reason, myg: myg})
+	}
+	return plan
+}
+
+func (s *Service) planMigrate(ctx context.Context, redoLast bool) (Plan, error) {
+	todo, err := s.findOpen(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(todo) == 0 && redoLast && len(s.migrations) >= 1 {
+		last := s.migrations[len(s.migrations)-1:]
+		plan := stepsOf(last, DirectionDown, reasonRedoLast)
+		return append(plan, stepsOf(last, DirectionUp, reasonRedoLast)...), nil
+	}
+
+	return stepsOf(todo, DirectionUp, reasonPending), nil
+}
+
+func (s *Service) planMigrateTo(ctx context.Context, id string) (Plan, error) {
+	target := s.indexOf(id)
+	if target < 0 {
+		return nil, errUnknownID(id)
+	}
+
+	todo, err := s.findOpen(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// findOpen keeps the order of registration, everything after the target is cut off
+	var upTo []mygration
+	for _, myg := range todo {
+		if s.indexOf(myg.ID) > target {
+			break
+		}
+		upTo = append(upTo, myg)
+	}
+
+	return stepsOf(upTo, DirectionUp, reasonPending), nil
+}
+
+func (s *Service) planSteps(ctx context.Context, n int) (Plan, error) {
+	if n >= 0 {
+		todo, err := s.findOpen(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if n < len(todo) {
+			todo = todo[:n]
+		}
+		return stepsOf(todo, DirectionUp, reasonPending), nil
+	}
+
+	// an empty target never matches, so all applied migrations are returned in reversed order
+	revert, err := s.findRevert(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	if -n < len(revert) {
+		revert = revert[:-n]
+	}
+	return stepsOf(revert, DirectionDown, reasonStepDown), nil
+}
+
+func (s *Service) planRedo(ctx context.Context) (Plan, error) {
+	// an empty target never matches, so the last applied migration comes first
+	revert, err := s.findRevert(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(revert) == 0 {
+		return nil, nil
+	}
+
+	last := revert[:1]
+	plan := stepsOf(last, DirectionDown, reasonRedo)
+	return append(plan, stepsOf(last, DirectionUp, reasonRedo)...), nil
+}
+
+func (s *Service) planRollback(ctx context.Context, id string, reason string) (Plan, error) {
+	todo, err := s.findRevert(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return stepsOf(todo, DirectionDown, reason), nil
+}
+
+func (s *Service) planReset(ctx context.Context, reason string) (Plan, error) {
+	if len(s.migrations) == 0 {
+		return nil, nil
+	}
+
+	return s.planRollback(ctx, s.migrations[0].ID, reason)
+}
+
+func (s *Service) planRefresh(ctx context.Context) (Plan, error) {
+	plan, err := s.planReset(ctx, reasonRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	// after a reset every registered migration is pending again
+	return append(plan, stepsOf(s.migrations, DirectionUp, reasonRefresh)...), nil
+}
+
+// PlanMigrate returns the migrations Migrate would execute, without executing them.
+func (s *Service) PlanMigrate(redoLast bool) (Plan, error) {
+	return s.PlanMigrateContext(context.Background(), redoLast)
+}
+
+// PlanMigrateContext is like PlanMigrate, but passes ctx to the store.
+func (s *Service) PlanMigrateContext(ctx context.Context, redoLast bool) (Plan, error) {
+	if err := s.init(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.planMigrate(ctx, redoLast)
+}
+
+// PlanMigrateTo returns the migrations MigrateTo would execute, without executing them.
+func (s *Service) PlanMigrateTo(id string) (Plan, error) {
+	return s.PlanMigrateToContext(context.Background(), id)
+}
+
+// PlanMigrateToContext is like PlanMigrateTo, but passes ctx to the store.
+func (s *Service) PlanMigrateToContext(ctx context.Context, id string) (Plan, error) {
+	if err := s.init(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.planMigrateTo(ctx, id)
+}
+
+// PlanSteps returns the migrations Steps would execute, without executing them.
+func (s *Service) PlanSteps(n int) (Plan, error) {
+	return s.PlanStepsContext(context.Background(), n)
+}
+
+// PlanStepsContext is like PlanSteps, but passes ctx to the store.
+func (s *Service) PlanStepsContext(ctx context.Context, n int) (Plan, error) {
+	if err := s.init(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.planSteps(ctx, n)
+}
+
+// PlanRedo returns the migrations Redo would execute, without executing them.
+func (s *Service) PlanRedo() (Plan, error) {
+	return s.PlanRedoContext(context.Background())
+}
+
+// PlanRedoContext is like PlanRedo, but passes ctx to the store.
+func (s *Service) PlanRedoContext(ctx context.Context) (Plan, error) {
+	if err := s.init(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.planRedo(ctx)
+}
+
+// PlanRollback returns the migrations Rollback would execute, without executing them.
+// Unlike Rollback, it returns ErrUnknownID if the id is not registered.
+func (s *Service) PlanRollback(id string) (Plan, error) {
+	return s.PlanRollbackContext(context.Background(), id)
+}
+
+// PlanRollbackContext is like PlanRollback, but passes ctx to the store.
+func (s *Service) PlanRollbackContext(ctx context.Context, id string) (Plan, error) {
+	if err := s.init(ctx); err != nil {
+		return nil, err
+	}
+
+	if s.indexOf(id) < 0 {
+		return nil, errUnknownID(id)
+	}
+
+	return s.planRollback(ctx, id, reasonRollbackTo+id)
+}
+
+// PlanReset returns the migrations Reset would execute, without executing them.
+func (s *Service) PlanReset() (Plan, error) {
+	return s.PlanResetContext(context.Background())
+}
+
+// PlanResetContext is like PlanReset, but passes ctx to the store.
+func (s *Service) PlanResetContext(ctx context.Context) (Plan, error) {
+	if err := s.init(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.planReset(ctx, reasonReset)
+}
+
+// PlanRefresh returns the migrations Refresh would execute, without executing them.
+func (s *Service) PlanRefresh() (Plan, error) {
+	return s.PlanRefreshContext(context.Background())
+}
+
+// PlanRefreshContext is like PlanRefresh, but passes ctx to the store.
+func (s *Service) PlanRefreshContext(ctx context.Context) (Plan, error) {
+	if err := s.init(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.planRefresh(ctx)
+}
@@ -0,0 +1,151 @@
+package mygrate_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lanz-dev/go-mygrate/mygrate"
+)
+
+func TestService_PlanMigrate(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	mock.FindDoneFunc = func() ([]string, error) {
+		return []string{"1"}, nil
+	}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	m.Register("1", errFunc, errFunc)
+	m.Register("2", errFunc, errFunc)
+	m.Register("3", errFunc, errFunc)
+
+	plan, err := m.PlanMigrate(true)
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	expected := "up   2 (pending)\nup   3 (pending)\n"
+	if plan.String() != expected {
+		t.Fatalf(`expected plan '%s', got '%s'`, expected, plan)
+	}
+	if mock.LockCalled {
+		t.Fatal(`did not expected mock.Lock() to be called`)
+	}
+}
+
+func TestService_PlanMigrate_RedoLast(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	mock.FindDoneFunc = func() ([]string, error) {
+		return []string{"1", "2"}, nil
+	}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	m.Register("1", errFunc, errFunc)
+	m.Register("2", errFunc, errFunc)
+
+	plan, err := m.PlanMigrate(true)
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	expected := mygrate.Plan{
+		{ID: "2", Direction: mygrate.DirectionDown, Reason: "redo last"},
+		{ID: "2", Direction: mygrate.DirectionUp, Reason: "redo last"},
+	}
+	assertPlan(t, expected, plan)
+}
+
+func TestService_PlanMigrate_FindDoneErr(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	mock.FindDoneFunc = func() ([]string, error) {
+		return nil, errUnitTest
+	}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	_, err := m.PlanMigrate(false)
+
+	if !errors.Is(err, mygrate.ErrStore) {
+		t.Fatalf(`expected err to be '%s'`, mygrate.ErrStore)
+	}
+}
+
+func TestService_PlanRollback(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	mock.FindDoneFunc = func() ([]string, error) {
+		return []string{"1", "3"}, nil
+	}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	m.Register("1", errFunc, errFunc)
+	m.Register("2", errFunc, errFunc)
+	m.Register("3", errFunc, errFunc)
+
+	plan, err := m.PlanRollback("2")
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	expected := mygrate.Plan{
+		{ID: "3", Direction: mygrate.DirectionDown, Reason: "rollback to 2"},
+	}
+	assertPlan(t, expected, plan)
+}
+
+func TestService_PlanRefresh(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	mock.FindDoneFunc = func() ([]string, error) {
+		return []string{"1"}, nil
+	}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	m.Register("1", errFunc, errFunc)
+	m.Register("2", errFunc, errFunc)
+
+	plan, err := m.PlanRefresh()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	expected := mygrate.Plan{
+		{ID: "1", Direction: mygrate.DirectionDown, Reason: "refresh"},
+		{ID: "1", Direction: mygrate.DirectionUp, Reason: "refresh"},
+		{ID: "2", Direction: mygrate.DirectionUp, Reason: "refresh"},
+	}
+	assertPlan(t, expected, plan)
+}
+
+func TestService_PlanReset_WithNoMigrationsRegistered(t *testing.T) {
+	t.Parallel()
+
+	m := mygrate.New(mygrate.WithStore(buildMock()))
+
+	plan, err := m.PlanReset()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if plan.String() != "nothing to do\n" {
+		t.Fatalf(`expected an empty plan, got '%s'`, plan)
+	}
+}
+
+func assertPlan(t *testing.T, expected, got mygrate.Plan) {
+	t.Helper()
+
+	if len(expected) != len(got) {
+		t.Fatalf(`expected plan '%s', got '%s'`, expected, got)
+	}
+	for i := range expected {
+		if expected[i].String() != got[i].String() {
+			t.Fatalf(`expected plan '%s', got '%s'`, expected, got)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package mygrate
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/lanz-dev/go-mygrate/store"
@@ -8,15 +13,35 @@
 
 // Service provides methods for Mμgrate.
 type Service struct {
+	// initMu guards initDone, the status may be read during a run.
+	initMu     sync.Mutex
 	initDone   bool
 	migrations []mygration
 	store      Store
+	hooks      hooks
+	logger     Logger
+	logLevel   Level
+	observers  []Observer
+	tracer     Tracer
+
+	// runTimeout limits each run, timeoutGrace the wait for a timed out func.
+	runTimeout   time.Duration
+	timeoutGrace time.Duration
+
+	// hostname, pid and version are recorded by a RecordStore.
+	hostname string
+	pid      int
+	version  string
 }
 
 // New will create a new Service instance with a default FileStore.
 func New(opts ...Option) *Service {
+	hostname, _ := os.Hostname()
 	s := &Service{
-		store: store.NewFileStore(),
+		store:    store.NewFileStore(),
+		hostname: hostname,
+		pid:      os.Getpid(),
+		logLevel: LevelInfo,
 	}
 
 	for _, opt := range opts {
@@ -26,44 +51,411 @@ func New(opts ...Option) *Service {
 	return s
 }
 
-func (s *Service) up(myg mygration) error {
-	if err := myg.Up(); err != nil {
-		return errUp(myg.ID, err)
+func (s *Service) storeInit(ctx context.Context) (err error) {
+	ctx, span := s.startSpan(ctx, SpanInit)
+	defer func() { span.End(err) }()
+
+	if cs, ok := s.store.(ContextStore); ok {
+		return cs.InitContext(ctx)
 	}
+	return s.store.Init()
+}
 
-	if err := s.store.Up(myg.ID, time.Now().UTC()); err != nil {
-		return errStore(myg.ID, err)
+func (s *Service) storeUp(ctx context.Context, rec store.Record) (err error) {
+	ctx, span := s.startSpan(ctx, SpanStoreUp, migrationAttrs(rec.ID, DirectionUp)...)
+	defer func() { span.End(err) }()
+
+	if rs, ok := s.store.(RecordStore); ok {
+		return rs.UpRecord(ctx, rec)
+	}
+	if cs, ok := s.store.(ContextStore); ok {
+		return cs.UpContext(ctx, rec.ID, rec.Executed)
 	}
+	return s.store.Up(rec.ID, rec.Executed)
+}
 
-	return nil
+func (s *Service) storeDown(ctx context.Context, rec store.Record) (err error) {
+	ctx, span := s.startSpan(ctx, SpanStoreDown, migrationAttrs(rec.ID, DirectionDown)...)
+	defer func() { span.End(err) }()
+
+	if rs, ok := s.store.(RecordStore); ok {
+		return rs.DownRecord(ctx, rec)
+	}
+	if cs, ok := s.store.(ContextStore); ok {
+		return cs.DownContext(ctx, rec.ID, rec.Executed)
+	}
+	return s.store.Down(rec.ID, rec.Executed)
 }
 
-func (s *Service) down(myg mygration) error {
-	if err := myg.Down(); err != nil {
-		return errDown(myg.ID, err)
+func (s *Service) storeRecordTx(ctx context.Context, ts TxStore, tx *sql.Tx, rec store.Record) (err error) {
+	name := SpanStoreDown
+	if rec.Direction == store.DirectionUp {
+		name = SpanStoreUp
+	}
+	ctx, span := s.startSpan(ctx, name, migrationAttrs(rec.ID, Direction(rec.Direction))...)
+	defer func() { span.End(err) }()
+
+	rs, ok := ts.(TxRecordStore)
+	switch {
+	case ok && rec.Direction == store.DirectionUp:
+		return rs.UpRecordTx(ctx, tx, rec)
+	case ok:
+		return rs.DownRecordTx(ctx, tx, rec)
+	case rec.Direction == store.DirectionUp:
+		return ts.UpTx(ctx, tx, rec.ID, rec.Executed)
+	default:
+		return ts.DownTx(ctx, tx, rec.ID, rec.Executed)
 	}
+}
+
+// detachedContext carries the values of its parent, e.g. the span of a Tracer,
+// but is never canceled.
+type detachedContext struct {
+	parent context.Context
+}
 
-	if err := s.store.Down(myg.ID, time.Now().UTC()); err != nil {
-		return errStore(myg.ID, err)
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// detach returns a context for the bookkeeping of an executed migration func,
+// which must not be interrupted by the cancellation of the run.
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+// newRecord describes the execution of a migration func which started at started and has just finished.
+// The record of an up func carries the checksum of myg.
+func (s *Service) newRecord(myg mygration, dir Direction, started time.Time) store.Record {
+	now := time.Now()
+	rec := store.Record{
+		ID:        myg.ID,
+		Direction: string(dir),
+		Executed:  now.UTC(),
+		Started:   started.UTC(),
+		Duration:  now.Sub(started),
+		Hostname:  s.hostname,
+		PID:       s.pid,
+		Version:   s.version,
+	}
+	if dir == DirectionUp {
+		rec.Checksum = myg.Checksum
 	}
+	return rec
+}
 
-	return nil
+func (s *Service) storeFindDone(ctx context.Context) (done []string, err error) {
+	ctx, span := s.startSpan(ctx, SpanFindDone)
+	defer func() { span.End(err) }()
+
+	if cs, ok := s.store.(ContextStore); ok {
+		return cs.FindDoneContext(ctx)
+	}
+	return s.store.FindDone()
 }
 
-func (s *Service) redo(key int) error {
-	if err := s.down(s.migrations[key]); err != nil {
-		return err
+// lock locks the store if it implements ContextLocker or Locker.
+// The returned func releases the lock and is never nil.
+func (s *Service) lock(ctx context.Context) (func(), error) {
+	start := time.Now()
+	s.log(LevelDebug, "acquiring lock")
+
+	unlock, err := s.lockStore(ctx)
+	if err != nil {
+		s.log(LevelError, "lock failed", "error", err)
+		return func() {}, err
 	}
 
-	if err := s.up(s.migrations[key]); err != nil {
-		return err
+	s.log(LevelDebug, "lock acquired", "wait", time.Since(start))
+	s.emit(ctx, Event{Type: EventLockAcquired, Duration: time.Since(start)})
+	return func() {
+		if err := unlock(); err != nil {
+			// e.g. store.ErrLockLost, someone else may have run migrations in between
+			s.log(LevelError, "unlock failed", "error", err)
+			return
+		}
+		s.log(LevelDebug, "lock released")
+	}, nil
+}
+
+func (s *Service) lockStore(ctx context.Context) (unlock func() error, err error) {
+	ctx, span := s.startSpan(ctx, SpanLock)
+	defer func() { span.End(err) }()
+
+	if locker, ok := s.store.(ContextLocker); ok {
+		if err := locker.LockContext(ctx); err != nil {
+			return noUnlock, errStore("", err)
+		}
+		// the run context may already be canceled, but the lock has to be released anyway
+		return func() error { return locker.UnlockContext(context.Background()) }, nil
 	}
 
+	if locker, ok := s.store.(Locker); ok {
+		if err := locker.Lock(); err != nil {
+			return noUnlock, errStore("", err)
+		}
+		return locker.Unlock, nil
+	}
+
+	return noUnlock, nil
+}
+
+func noUnlock() error {
 	return nil
 }
 
-func (s *Service) findOpen() ([]mygration, error) {
-	doneIDs, err := s.store.FindDone()
+// up executes the up func of myg and records it with the store, succeeded is
+// called in between. The returned record describes the execution, even if it failed.
+func (s *Service) up(ctx context.Context, myg mygration, succeeded func()) (store.Record, error) {
+	rec := s.newRecord(myg, DirectionUp, time.Now())
+	if err := ctx.Err(); err != nil {
+		return rec, errUp(myg.ID, err)
+	}
+
+	if myg.UpTx != nil {
+		var err error
+		if rec, err = s.runTx(ctx, myg, DirectionUp, myg.UpTx, errUp, succeeded); err != nil {
+			return rec, err
+		}
+		return rec, s.setChecksumAfterUp(detach(ctx), myg)
+	}
+
+	if myg.Up == nil {
+		return rec, errUp(myg.ID, ErrNilFunc)
+	}
+
+	started := time.Now()
+	fnCtx, cancel := s.migrationContext(ctx, myg.Timeout)
+	fnCtx, span := s.startSpan(fnCtx, SpanUp, migrationAttrs(myg.ID, DirectionUp)...)
+	err := callTimeout(fnCtx, s.timeoutGrace, myg.Up)
+	span.End(err)
+	cancel()
+	rec = s.newRecord(myg, DirectionUp, started)
+	if err != nil {
+		return rec, errUp(myg.ID, err)
+	}
+	succeeded()
+
+	// the migration is applied, so it has to be recorded even if the run is canceled now
+	ctx = detach(ctx)
+	if err := s.storeUp(ctx, rec); err != nil {
+		return rec, errStore(myg.ID, err)
+	}
+
+	return rec, s.setChecksumAfterUp(ctx, myg)
+}
+
+// down executes the down func of myg and records it with the store, succeeded is
+// called in between. The returned record describes the execution, even if it failed.
+func (s *Service) down(ctx context.Context, myg mygration, succeeded func()) (store.Record, error) {
+	rec := s.newRecord(myg, DirectionDown, time.Now())
+	if err := ctx.Err(); err != nil {
+		return rec, errDown(myg.ID, err)
+	}
+
+	if myg.DownTx != nil {
+		return s.runTx(ctx, myg, DirectionDown, myg.DownTx, errDown, succeeded)
+	}
+
+	if myg.Down == nil {
+		return rec, errDown(myg.ID, ErrNilFunc)
+	}
+
+	started := time.Now()
+	fnCtx, cancel := s.migrationContext(ctx, myg.Timeout)
+	fnCtx, span := s.startSpan(fnCtx, SpanDown, migrationAttrs(myg.ID, DirectionDown)...)
+	err := callTimeout(fnCtx, s.timeoutGrace, myg.Down)
+	span.End(err)
+	cancel()
+	rec = s.newRecord(myg, DirectionDown, started)
+	if err != nil {
+		return rec, errDown(myg.ID, err)
+	}
+	succeeded()
+
+	// the migration is rolled back, so it has to be recorded even if the run is canceled now
+	if err := s.storeDown(detach(ctx), rec); err != nil {
+		return rec, errStore(myg.ID, err)
+	}
+
+	return rec, nil
+}
+
+// runTx executes fn of a transactional migration and records it with the store
+// in the same transaction, so both are either committed or rolled back.
+func (s *Service) runTx(
+	ctx context.Context,
+	myg mygration,
+	dir Direction,
+	fn func(context.Context, *sql.Tx) error,
+	fnErr func(string, error) error,
+	succeeded func(),
+) (store.Record, error) {
+	id := myg.ID
+	rec := s.newRecord(myg, dir, time.Now())
+	ts, ok := s.store.(TxStore)
+	if !ok {
+		return rec, fnErr(id, ErrTxUnsupported)
+	}
+
+	// the timeout covers the whole transaction, the sql package rolls it back once it is exceeded
+	txCtx, cancel := s.migrationContext(ctx, myg.Timeout)
+	defer cancel()
+	tx, err := ts.BeginTx(txCtx)
+	if err != nil {
+		return rec, errStore(id, err)
+	}
+
+	name := SpanDown
+	if dir == DirectionUp {
+		name = SpanUp
+	}
+	started := time.Now()
+	fnCtx, span := s.startSpan(txCtx, name, migrationAttrs(id, dir)...)
+	err = callTimeout(fnCtx, s.timeoutGrace, func(ctx context.Context) error {
+		return fn(ctx, tx)
+	})
+	span.End(err)
+	rec = s.newRecord(myg, dir, started)
+	if errors.Is(err, ErrStillRunning) {
+		// fn may still use tx, so the rollback must not block until it returns
+		go func() { _ = tx.Rollback() }()
+		return rec, fnErr(id, err)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return rec, fnErr(id, err)
+	}
+	succeeded()
+
+	if err := s.storeRecordTx(ctx, ts, tx, rec); err != nil {
+		_ = tx.Rollback()
+		return rec, errStore(id, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return rec, errStore(id, err)
+	}
+
+	return rec, nil
+}
+
+// run locks the store, plans the run with planFn and applies the plan. All
+// errors are logged and passed to the OnError hooks before the lock is released.
+func (s *Service) run(ctx context.Context, planFn func(context.Context) (Plan, error)) (Plan, error) {
+	ctx = withRunDeadline(ctx)
+	unlock, err := s.lock(ctx)
+	if err != nil {
+		return nil, s.fail(ctx, PlanStep{}, err)
+	}
+	defer unlock()
+
+	plan, err := planFn(ctx)
+	if err != nil {
+		return nil, s.fail(ctx, PlanStep{}, err)
+	}
+
+	if failed, err := s.apply(ctx, plan); err != nil {
+		return nil, s.fail(ctx, failed, err)
+	}
+
+	return plan, nil
+}
+
+// fail logs the error of a run and calls the OnError hooks. step is the failed
+// step, which is empty if the run failed before or after its migrations, then
+// the ID is taken from err, e.g. of a checksum mismatch.
+func (s *Service) fail(ctx context.Context, step PlanStep, err error) error {
+	var merr *Error
+	if step.ID == "" && errors.As(err, &merr) {
+		step.ID = merr.ID
+	}
+	s.log(LevelError, "run failed", "id", step.ID, "direction", step.Direction, "error", err)
+	s.hooks.runError(ctx, step.ID, step.Direction, err)
+	return err
+}
+
+// apply executes the steps of plan and calls the hooks and observers around
+// them. It returns the failed step, see applySteps.
+func (s *Service) apply(ctx context.Context, plan Plan) (PlanStep, error) {
+	start := time.Now()
+	s.emit(ctx, Event{Type: EventRunStarted, Total: len(plan)})
+	if len(plan) == 0 {
+		s.emit(ctx, Event{Type: EventRunFinished, Duration: time.Since(start)})
+		return PlanStep{}, nil
+	}
+
+	s.log(LevelInfo, "run started", "migrations", len(plan))
+	s.startRunDeadline(ctx)
+
+	failed, err := s.applySteps(ctx, plan)
+	s.emit(ctx, Event{Type: EventRunFinished, Total: len(plan), Duration: time.Since(start), Err: err})
+	if err != nil {
+		return failed, err
+	}
+
+	s.log(LevelInfo, "run finished", "migrations", len(plan), "duration", time.Since(start))
+	return PlanStep{}, nil
+}
+
+// applySteps returns the failed step, which is empty if a RunHook failed.
+func (s *Service) applySteps(ctx context.Context, plan Plan) (PlanStep, error) {
+	if err := s.hooks.runAll(ctx, s.hooks.beforeAll, plan); err != nil {
+		return PlanStep{}, err
+	}
+
+	for i, step := range plan {
+		if err := s.hooks.runEach(ctx, s.hooks.beforeEach, step); err != nil {
+			return step, err
+		}
+
+		run := s.up
+		if step.Direction == DirectionDown {
+			run = s.down
+		}
+		started := time.Now()
+		s.log(LevelDebug, "migration started", "id", step.ID, "direction", step.Direction, "reason", step.Reason)
+		s.emit(ctx, stepEvent(EventMigrationStarted, plan, i, started))
+
+		rec, err := run(ctx, step.myg, func() {
+			s.emit(ctx, stepEvent(EventMigrationSucceeded, plan, i, started))
+		})
+		if err != nil {
+			e := stepEvent(EventMigrationFailed, plan, i, started)
+			e.Err = err
+			s.emit(ctx, e)
+		} else {
+			s.log(LevelInfo, "migration finished", "id", step.ID, "direction", step.Direction, "duration", rec.Duration)
+			s.emit(ctx, stepEvent(EventStoreUpdated, plan, i, started))
+		}
+
+		if herr := s.appendHistory(ctx, step, rec, err); herr != nil && err == nil {
+			return step, herr
+		}
+		if err != nil {
+			return step, err
+		}
+
+		if err := s.hooks.runEach(ctx, s.hooks.afterEach, step); err != nil {
+			return step, err
+		}
+	}
+
+	return PlanStep{}, s.hooks.runAll(ctx, s.hooks.afterAll, plan)
+}
+
+func (s *Service) indexOf(id string) int {
+	for i, myg := range s.migrations {
+		if myg.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Service) findOpen(ctx context.Context) ([]mygration, error) {
+	doneIDs, err := s.storeFindDone(ctx)
 	if err != nil {
 		return nil, errStore("", err)
 	}
@@ -82,11 +474,15 @@ func (s *Service) findOpen() ([]mygration, error) {
 		}
 	}
 
+	if err := s.verifyChecksums(ctx); err != nil {
+		return nil, err
+	}
+
 	return todo, nil
 }
 
-func (s *Service) findRevert(targetID string) ([]mygration, error) {
-	doneIDs, err := s.store.FindDone()
+func (s *Service) findRevert(ctx context.Context, targetID string) ([]mygration, error) {
+	doneIDs, err := s.storeFindDone(ctx)
 	if err != nil {
 		return nil, errStore("", err)
 	}
@@ -113,112 +509,227 @@ func (s *Service) findRevert(targetID string) ([]mygration, error) {
 	return revert, nil
 }
 
-func (s *Service) init() error {
+// recovered returns the error of a RecoveringStore, which describes its repaired state.
+func (s *Service) recovered() error {
+	if rs, ok := s.store.(RecoveringStore); ok {
+		return rs.Recovered()
+	}
+	return nil
+}
+
+func (s *Service) init(ctx context.Context) error {
+	if errs := s.validateIDs(); len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+
+	s.initMu.Lock()
+	defer s.initMu.Unlock()
 	if s.initDone {
 		return nil
 	}
 
-	if err := s.store.Init(); err != nil {
+	if err := s.storeInit(ctx); err != nil {
+		s.log(LevelError, "init failed", "error", err)
 		return errInit(err)
 	}
 
 	s.initDone = true
+	s.log(LevelDebug, "store initialized")
+	if err := s.recovered(); err != nil {
+		s.log(LevelError, "store recovered from a corrupt state", "error", err)
+	}
 
 	return nil
 }
 
 // Migrate will execute all outstanding migrations.
 func (s *Service) Migrate(redoLast bool) (int, error) {
-	if err := s.init(); err != nil {
+	return s.MigrateContext(context.Background(), redoLast)
+}
+
+// MigrateContext is like Migrate, but passes ctx to the store and the migrations.
+func (s *Service) MigrateContext(ctx context.Context, redoLast bool) (int, error) {
+	if err := s.init(ctx); err != nil {
 		return 0, err
 	}
 
-	locker, ok := s.store.(Locker)
-	if ok {
-		if err := locker.Lock(); err != nil {
-			return 0, errStore("", err)
+	plan, err := s.run(ctx, func(ctx context.Context) (Plan, error) {
+		plan, err := s.planMigrate(ctx, redoLast)
+		if err == nil && len(plan) > 0 && plan[0].Reason == reasonRedoLast {
+			s.log(LevelInfo, "nothing pending, redo the last migration", "id", plan[0].ID)
 		}
-		defer locker.Unlock()
+		return plan, err
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	todo, err := s.findOpen()
+	return plan.pending(), nil
+}
+
+// MigrateTo will execute all outstanding migrations up to (including) the given id.
+func (s *Service) MigrateTo(id string) (int, error) {
+	return s.MigrateToContext(context.Background(), id)
+}
+
+// MigrateToContext is like MigrateTo, but passes ctx to the store and the migrations.
+func (s *Service) MigrateToContext(ctx context.Context, id string) (int, error) {
+	if err := s.init(ctx); err != nil {
+		return 0, err
+	}
+
+	plan, err := s.run(ctx, func(ctx context.Context) (Plan, error) {
+		return s.planMigrateTo(ctx, id)
+	})
 	if err != nil {
 		return 0, err
 	}
 
-	for _, myg := range todo {
-		if err := s.up(myg); err != nil {
-			return 0, err
-		}
+	return plan.pending(), nil
+}
+
+// Steps will execute the next n outstanding migrations if n is positive,
+// or rollback the last -n applied migrations if n is negative.
+// It returns the number of executed migrations.
+func (s *Service) Steps(n int) (int, error) {
+	return s.StepsContext(context.Background(), n)
+}
+
+// StepsContext is like Steps, but passes ctx to the store and the migrations.
+func (s *Service) StepsContext(ctx context.Context, n int) (int, error) {
+	if err := s.init(ctx); err != nil {
+		return 0, err
+	}
+
+	plan, err := s.run(ctx, func(ctx context.Context) (Plan, error) {
+		return s.planSteps(ctx, n)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(plan), nil
+}
+
+// Redo will rollback the last applied migration and execute it again.
+// It returns false if no migration is applied.
+func (s *Service) Redo() (bool, error) {
+	return s.RedoContext(context.Background())
+}
+
+// RedoContext is like Redo, but passes ctx to the store and the migrations.
+func (s *Service) RedoContext(ctx context.Context) (bool, error) {
+	if err := s.init(ctx); err != nil {
+		return false, err
 	}
 
-	changes := len(todo)
-	if changes == 0 && redoLast && len(s.migrations) >= 1 {
-		lastKey := len(s.migrations) - 1
-		if err := s.redo(lastKey); err != nil {
-			return 0, err
+	plan, err := s.run(ctx, func(ctx context.Context) (Plan, error) {
+		plan, err := s.planRedo(ctx)
+		if err == nil && len(plan) == 0 {
+			s.log(LevelInfo, "nothing applied, nothing to redo")
 		}
+		return plan, err
+	})
+	if err != nil {
+		return false, err
 	}
 
-	return changes, nil
+	return len(plan) > 0, nil
 }
 
 // Rollback will rollback migrations to (including) the given id.
 func (s *Service) Rollback(id string) error {
-	if err := s.init(); err != nil {
+	return s.RollbackContext(context.Background(), id)
+}
+
+// RollbackContext is like Rollback, but passes ctx to the store and the migrations.
+func (s *Service) RollbackContext(ctx context.Context, id string) error {
+	if err := s.init(ctx); err != nil {
 		return err
 	}
 
-	locker, ok := s.store.(Locker)
-	if ok {
-		if err := locker.Lock(); err != nil {
-			return errStore("", err)
-		}
-		defer locker.Unlock()
+	_, err := s.rollback(ctx, id)
+	return err
+}
+
+// RollbackTo is like Rollback, but returns the number of rolled back migrations.
+// Unlike Rollback, it returns ErrUnknownID if the id is not registered.
+func (s *Service) RollbackTo(id string) (int, error) {
+	return s.RollbackToContext(context.Background(), id)
+}
+
+// RollbackToContext is like RollbackTo, but passes ctx to the store and the migrations.
+func (s *Service) RollbackToContext(ctx context.Context, id string) (int, error) {
+	if err := s.init(ctx); err != nil {
+		return 0, err
 	}
 
-	todo, err := s.findRevert(id)
-	if err != nil {
-		return err
+	if s.indexOf(id) < 0 {
+		return 0, errUnknownID(id)
 	}
 
-	for _, myg := range todo {
-		if err := s.down(myg); err != nil {
-			return err
-		}
+	return s.rollback(ctx, id)
+}
+
+// rollback rolls back the migrations to id and returns their number.
+func (s *Service) rollback(ctx context.Context, id string) (int, error) {
+	plan, err := s.run(ctx, func(ctx context.Context) (Plan, error) {
+		return s.planRollback(ctx, id, reasonRollbackTo+id)
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	return len(plan), nil
 }
 
 // Reset will rollback all migrations.
 func (s *Service) Reset() error {
-	if err := s.init(); err != nil {
-		return err
-	}
+	return s.ResetContext(context.Background())
+}
 
-	if len(s.migrations) == 0 {
-		return nil
+// ResetContext is like Reset, but passes ctx to the store and the migrations.
+func (s *Service) ResetContext(ctx context.Context) error {
+	_, err := s.RollbackAllContext(ctx)
+	return err
+}
+
+// RollbackAll is like Reset, but returns the number of rolled back migrations.
+func (s *Service) RollbackAll() (int, error) {
+	return s.RollbackAllContext(context.Background())
+}
+
+// RollbackAllContext is like RollbackAll, but passes ctx to the store and the migrations.
+func (s *Service) RollbackAllContext(ctx context.Context) (int, error) {
+	if err := s.init(ctx); err != nil {
+		return 0, err
 	}
 
-	if err := s.Rollback(s.migrations[0].ID); err != nil {
-		return err
+	if len(s.migrations) == 0 {
+		return 0, nil
 	}
 
-	return nil
+	return s.rollback(ctx, s.migrations[0].ID)
 }
 
 // Refresh will rollback all migrations and execute them again.
 func (s *Service) Refresh() error {
-	if err := s.init(); err != nil {
+	return s.RefreshContext(context.Background())
+}
+
+// RefreshContext is like Refresh, but passes ctx to the store and the migrations.
+func (s *Service) RefreshContext(ctx context.Context) error {
+	if err := s.init(ctx); err != nil {
 		return err
 	}
 
-	if err := s.Reset(); err != nil {
+	// the rollback and the migrate share the deadline of the run
+	ctx = withRunDeadline(ctx)
+	if err := s.ResetContext(ctx); err != nil {
 		return err
 	}
 
-	if _, err := s.Migrate(false); err != nil {
+	if _, err := s.MigrateContext(ctx, false); err != nil {
 		return err
 	}
 
@@ -227,9 +738,45 @@ func (s *Service) Refresh() error {
 
 // Register will register a migration.
 func (s *Service) Register(id string, up func() error, down func() error) {
-	s.migrations = append(s.migrations, mygration{
+	s.RegisterContext(id, withoutContext(up), withoutContext(down))
+}
+
+// RegisterContext will register a migration whose funcs receive the context
+// which was passed to MigrateContext, RollbackContext, etc.
+func (s *Service) RegisterContext(
+	id string,
+	up func(context.Context) error,
+	down func(context.Context) error,
+	opts ...MigrationOption,
+) {
+	s.register(mygration{
 		ID:   id,
 		Up:   up,
 		Down: down,
-	})
+	}, opts)
+}
+
+// RegisterTx will register a transactional migration. The funcs receive a
+// transaction, which is committed together with the bookkeeping of the store,
+// so the store has to implement TxStore (e.g. store.SQLStore).
+// Statements which cannot run inside a transaction should be registered with
+// RegisterContext instead.
+func (s *Service) RegisterTx(
+	id string,
+	up func(context.Context, *sql.Tx) error,
+	down func(context.Context, *sql.Tx) error,
+	opts ...MigrationOption,
+) {
+	s.register(mygration{
+		ID:     id,
+		UpTx:   up,
+		DownTx: down,
+	}, opts)
+}
+
+func (s *Service) register(myg mygration, opts []MigrationOption) {
+	for _, opt := range opts {
+		opt(&myg)
+	}
+	s.migrations = append(s.migrations, myg)
 }
@@ -1,6 +1,7 @@
 package mygrate_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -892,3 +893,467 @@ func TestService_Refresh(t *testing.T) {
 		t.Fatalf(`did not expected err '%s'`, err)
 	}
 }
+
+type ctxKey struct{}
+
+// contextMock wraps a MockStore and implements mygrate.ContextStore and mygrate.ContextLocker.
+type contextMock struct {
+	*store.MockStore
+	ctxValues []interface{}
+}
+
+func (c *contextMock) InitContext(ctx context.Context) error {
+	c.ctxValues = append(c.ctxValues, ctx.Value(ctxKey{}))
+	return c.Init()
+}
+
+func (c *contextMock) UpContext(ctx context.Context, id string, executed time.Time) error {
+	c.ctxValues = append(c.ctxValues, ctx.Value(ctxKey{}))
+	return c.Up(id, executed)
+}
+
+func (c *contextMock) DownContext(ctx context.Context, id string, executed time.Time) error {
+	c.ctxValues = append(c.ctxValues, ctx.Value(ctxKey{}))
+	return c.Down(id, executed)
+}
+
+func (c *contextMock) FindDoneContext(ctx context.Context) ([]string, error) {
+	c.ctxValues = append(c.ctxValues, ctx.Value(ctxKey{}))
+	return c.FindDone()
+}
+
+func (c *contextMock) LockContext(ctx context.Context) error {
+	c.ctxValues = append(c.ctxValues, ctx.Value(ctxKey{}))
+	return c.Lock()
+}
+
+func (c *contextMock) UnlockContext(ctx context.Context) error {
+	return c.Unlock()
+}
+
+func TestService_MigrateContext_PassesContext(t *testing.T) {
+	t.Parallel()
+
+	mock := &contextMock{MockStore: buildMock()}
+	mock.UpFunc = func(id string, executed time.Time) error {
+		return nil
+	}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	var got interface{}
+	m.RegisterContext(
+		"1",
+		func(ctx context.Context) error {
+			got = ctx.Value(ctxKey{})
+			return nil
+		},
+		nil,
+	)
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	if _, err := m.MigrateContext(ctx, false); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	if got != "value" {
+		t.Fatalf(`expected migration to receive the context value, got '%v'`, got)
+	}
+	// init, lock, find done, up
+	if len(mock.ctxValues) != 4 {
+		t.Fatalf(`expected '%d' context calls on the store, got '%d'`, 4, len(mock.ctxValues))
+	}
+	for _, v := range mock.ctxValues {
+		if v != "value" {
+			t.Fatalf(`expected store to receive the context value, got '%v'`, v)
+		}
+	}
+	if !mock.UnlockCalled {
+		t.Fatal(`expected mock.Unlock() to be called`)
+	}
+}
+
+func TestService_MigrateContext_Canceled(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	mock.UpFunc = func(id string, executed time.Time) error {
+		return nil
+	}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	counter := 0
+	m.RegisterContext(
+		"1",
+		func(ctx context.Context) error {
+			counter++
+			cancel()
+			return nil
+		},
+		nil,
+	)
+	m.RegisterContext(
+		"2",
+		func(ctx context.Context) error {
+			counter++
+			return nil
+		},
+		nil,
+	)
+
+	_, err := m.MigrateContext(ctx, false)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf(`expected err to be '%s', got '%s'`, context.Canceled, err)
+	}
+	if !errors.Is(err, mygrate.ErrUpFn) {
+		t.Fatalf(`expected err to be '%s'`, mygrate.ErrUpFn)
+	}
+	if counter != 1 {
+		t.Fatalf(`expected counter to be '%d', got '%d'`, 1, counter)
+	}
+	if !mock.UnlockCalled {
+		t.Fatal(`expected mock.Unlock() to be called`)
+	}
+}
+
+// ctxStore fails to record migrations with a canceled context, like the SQLStore does.
+type ctxStore struct {
+	*store.MemoryStore
+}
+
+func (s ctxStore) UpRecord(ctx context.Context, rec store.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.MemoryStore.UpRecord(ctx, rec)
+}
+
+func (s ctxStore) DownRecord(ctx context.Context, rec store.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.MemoryStore.DownRecord(ctx, rec)
+}
+
+func (s ctxStore) SetChecksum(ctx context.Context, id string, checksum string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.MemoryStore.SetChecksum(ctx, id, checksum)
+}
+
+func TestService_MigrateContext_CanceledAfterSuccess(t *testing.T) {
+	t.Parallel()
+
+	mem := store.NewMemoryStore()
+	m := mygrate.New(mygrate.WithStore(ctxStore{mem}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelFunc := func(context.Context) error {
+		cancel()
+		return nil
+	}
+	m.RegisterContext("1", cancelFunc, cancelFunc, mygrate.WithChecksum("sum"))
+
+	if _, err := m.MigrateContext(ctx, false); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if done, _ := mem.FindDone(); len(done) != 1 {
+		t.Fatalf(`expected the applied migration to be recorded, got '%v'`, done)
+	}
+	if sums, _ := mem.FindChecksums(context.Background()); sums["1"] != "sum" {
+		t.Fatalf(`expected the checksum to be recorded, got '%v'`, sums)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	if err := m.ResetContext(ctx); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if done, _ := mem.FindDone(); len(done) != 0 {
+		t.Fatalf(`expected the rolled back migration to be recorded, got '%v'`, done)
+	}
+}
+
+func TestService_RollbackContext_PassesContext(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	mock.FindDoneFunc = func() ([]string, error) {
+		return []string{"1"}, nil
+	}
+	mock.DownFunc = func(id string, executed time.Time) error {
+		return nil
+	}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	var got interface{}
+	m.RegisterContext(
+		"1",
+		nil,
+		func(ctx context.Context) error {
+			got = ctx.Value(ctxKey{})
+			return nil
+		},
+	)
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	if err := m.RollbackContext(ctx, "1"); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	if got != "value" {
+		t.Fatalf(`expected migration to receive the context value, got '%v'`, got)
+	}
+}
+
+func TestService_MigrateTo(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	mock.FindDoneFunc = func() ([]string, error) {
+		return []string{"2"}, nil
+	}
+	var ups []string
+	mock.UpFunc = func(id string, executed time.Time) error {
+		ups = append(ups, id)
+		return nil
+	}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	m.Register("1", nilFunc, nilFunc)
+	m.Register("2", nilFunc, nilFunc)
+	m.Register("3", nilFunc, nilFunc)
+	m.Register("4", nilFunc, nilFunc)
+
+	changes, err := m.MigrateTo("3")
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if changes != 2 {
+		t.Fatalf(`expected changes to be '%d', got '%d'`, 2, changes)
+	}
+	if len(ups) != 2 || ups[0] != "1" || ups[1] != "3" {
+		t.Fatalf(`expected '1' and '3' to be migrated, got '%v'`, ups)
+	}
+	if !mock.UnlockCalled {
+		t.Fatal(`expected mock.Unlock() to be called`)
+	}
+}
+
+func TestService_MigrateTo_UnknownID(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	m.Register("1", errFunc, nilFunc)
+
+	_, err := m.MigrateTo("2")
+
+	if !errors.Is(err, mygrate.ErrUnknownID) {
+		t.Fatalf(`expected err to be '%s', got '%s'`, mygrate.ErrUnknownID, err)
+	}
+}
+
+func TestService_MigrateTo_MigrationUpErr(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	m.Register("1", errFunc, nilFunc)
+
+	_, err := m.MigrateTo("1")
+
+	if !errors.Is(err, errUnitTest) {
+		t.Fatalf(`expected err to be '%s'`, errUnitTest)
+	}
+	if !errors.Is(err, mygrate.ErrUpFn) {
+		t.Fatalf(`expected err to be '%s'`, mygrate.ErrUpFn)
+	}
+}
+
+func TestService_Steps_Up(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	mock.FindDoneFunc = func() ([]string, error) {
+		return []string{"1"}, nil
+	}
+	var ups []string
+	mock.UpFunc = func(id string, executed time.Time) error {
+		ups = append(ups, id)
+		return nil
+	}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	m.Register("1", errFunc, errFunc)
+	m.Register("2", nilFunc, errFunc)
+	m.Register("3", nilFunc, errFunc)
+	m.Register("4", nilFunc, errFunc)
+
+	changes, err := m.Steps(2)
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if changes != 2 {
+		t.Fatalf(`expected changes to be '%d', got '%d'`, 2, changes)
+	}
+	if len(ups) != 2 || ups[0] != "2" || ups[1] != "3" {
+		t.Fatalf(`expected '2' and '3' to be migrated, got '%v'`, ups)
+	}
+}
+
+func TestService_Steps_Down(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	mock.FindDoneFunc = func() ([]string, error) {
+		return []string{"1", "2", "3"}, nil
+	}
+	var downs []string
+	mock.DownFunc = func(id string, executed time.Time) error {
+		downs = append(downs, id)
+		return nil
+	}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	m.Register("1", errFunc, nilFunc)
+	m.Register("2", errFunc, nilFunc)
+	m.Register("3", errFunc, nilFunc)
+	m.Register("4", errFunc, errFunc)
+
+	changes, err := m.Steps(-5)
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if changes != 3 {
+		t.Fatalf(`expected changes to be '%d', got '%d'`, 3, changes)
+	}
+	if len(downs) != 3 || downs[0] != "3" || downs[2] != "1" {
+		t.Fatalf(`expected '3', '2' and '1' to be rolled back, got '%v'`, downs)
+	}
+}
+
+func TestService_Steps_LockError(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	mock.LockFunc = func() error {
+		return errUnitTest
+	}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	_, err := m.Steps(-1)
+
+	if !errors.Is(err, errUnitTest) {
+		t.Fatalf(`expected err to be '%s'`, errUnitTest)
+	}
+	if !errors.Is(err, mygrate.ErrStore) {
+		t.Fatalf(`expected err to be '%s'`, mygrate.ErrStore)
+	}
+}
+
+func TestService_Steps_MigrationDownErr(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	mock.FindDoneFunc = func() ([]string, error) {
+		return []string{"1"}, nil
+	}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	m.Register("1", nilFunc, errFunc)
+
+	_, err := m.Steps(-1)
+
+	if !errors.Is(err, mygrate.ErrDownFn) {
+		t.Fatalf(`expected err to be '%s'`, mygrate.ErrDownFn)
+	}
+}
+
+func TestService_Redo(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	mock.FindDoneFunc = func() ([]string, error) {
+		return []string{"1", "2"}, nil
+	}
+	var calls []string
+	mock.DownFunc = func(id string, executed time.Time) error {
+		calls = append(calls, "down "+id)
+		return nil
+	}
+	mock.UpFunc = func(id string, executed time.Time) error {
+		calls = append(calls, "up "+id)
+		return nil
+	}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	m.Register("1", errFunc, errFunc)
+	m.Register("2", nilFunc, nilFunc)
+	m.Register("3", errFunc, errFunc)
+
+	redone, err := m.Redo()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if !redone {
+		t.Fatal(`expected a migration to be redone`)
+	}
+	if len(calls) != 2 || calls[0] != "down 2" || calls[1] != "up 2" {
+		t.Fatalf(`expected '2' to be redone, got '%v'`, calls)
+	}
+}
+
+func TestService_Redo_NothingApplied(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	m.Register("1", errFunc, errFunc)
+
+	redone, err := m.Redo()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if redone {
+		t.Fatal(`did not expected a migration to be redone`)
+	}
+}
+
+func TestService_RollbackTo(t *testing.T) {
+	t.Parallel()
+
+	m := mygrate.New(mygrate.WithStore(store.NewMemoryStore()))
+	m.Register("1", nilFunc, nilFunc)
+	m.Register("2", nilFunc, nilFunc)
+	m.Register("3", nilFunc, nilFunc)
+
+	if _, err := m.Migrate(false); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	if _, err := m.RollbackTo("4"); !errors.Is(err, mygrate.ErrUnknownID) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, mygrate.ErrUnknownID, err)
+	}
+
+	n, err := m.RollbackTo("2")
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if n != 2 {
+		t.Fatalf(`expected '%d' rolled back migrations, got '%d'`, 2, n)
+	}
+
+	n, err = m.RollbackAll()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if n != 1 {
+		t.Fatalf(`expected '%d' rolled back migrations, got '%d'`, 1, n)
+	}
+}
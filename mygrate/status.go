@@ -0,0 +1,193 @@
+package mygrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+// MigrationStatus describes the state of a registered migration.
+type MigrationStatus struct {
+	ID    string `json:"id"`
+	Index int    `json:"index"` // Index in the order of registration.
+
+	Applied bool `json:"applied"`
+	// Executed is zero if the migration is pending or the store does not implement ExecutedFinder.
+	Executed time.Time `json:"executed"`
+
+	// Checksum is the registered checksum, see WithChecksum.
+	Checksum string `json:"checksum,omitempty"`
+	// Drifted is set if the checksum recorded by a ChecksumStore differs from the registered one.
+	Drifted bool `json:"drifted,omitempty"`
+
+	// Record is set for applied migrations if the store implements RecordStore.
+	Record *store.Record `json:"record,omitempty"`
+}
+
+// StatusReport describes the state of all registered migrations.
+type StatusReport struct {
+	Migrations []MigrationStatus `json:"migrations"`
+	// Unknown contains IDs which exist in the store, but are not registered.
+	Unknown []string `json:"unknown"`
+	// Recovered describes the corrupt state a RecoveringStore has repaired, the
+	// last applied or rolled back migration may be missing from the report.
+	Recovered string `json:"recovered,omitempty"`
+}
+
+// Applied returns all applied migrations in the order of registration.
+func (r StatusReport) Applied() []MigrationStatus {
+	var applied []MigrationStatus
+	for _, m := range r.Migrations {
+		if m.Applied {
+			applied = append(applied, m)
+		}
+	}
+	return applied
+}
+
+// Pending returns all pending migrations in the order of registration.
+func (r StatusReport) Pending() []MigrationStatus {
+	var pending []MigrationStatus
+	for _, m := range r.Migrations {
+		if !m.Applied {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// Drifted returns all applied migrations whose checksum has changed.
+func (r StatusReport) Drifted() []MigrationStatus {
+	var drifted []MigrationStatus
+	for _, m := range r.Migrations {
+		if m.Drifted {
+			drifted = append(drifted, m)
+		}
+	}
+	return drifted
+}
+
+// String returns a printable table of the report.
+func (r StatusReport) String() string {
+	var sb strings.Builder
+	for _, m := range r.Migrations {
+		state, executed := "pending", ""
+		if m.Applied {
+			state = "applied"
+			if m.Drifted {
+				state = "drifted"
+			}
+			if !m.Executed.IsZero() {
+				executed = m.Executed.Format(time.RFC3339)
+			}
+		}
+		fmt.Fprintf(&sb, "%-8s %-25s %s\n", state, executed, m.ID)
+	}
+	for _, id := range r.Unknown {
+		fmt.Fprintf(&sb, "%-8s %-25s %s\n", "unknown", "", id)
+	}
+	if r.Recovered != "" {
+		fmt.Fprintf(&sb, "warning: %s\n", r.Recovered)
+	}
+	return sb.String()
+}
+
+func (s *Service) findExecuted(ctx context.Context) (map[string]time.Time, error) {
+	if finder, ok := s.store.(ExecutedFinder); ok {
+		return finder.FindExecuted(ctx)
+	}
+
+	doneIDs, err := s.storeFindDone(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	executed := make(map[string]time.Time, len(doneIDs))
+	for _, id := range doneIDs {
+		executed[id] = time.Time{}
+	}
+	return executed, nil
+}
+
+// Status returns which registered migrations are applied or pending and
+// which IDs in the store are unknown.
+func (s *Service) Status() (StatusReport, error) {
+	return s.StatusContext(context.Background())
+}
+
+// StatusContext is like Status, but passes ctx to the store.
+func (s *Service) StatusContext(ctx context.Context) (StatusReport, error) {
+	if err := s.init(ctx); err != nil {
+		return StatusReport{}, err
+	}
+
+	return s.status(ctx)
+}
+
+// ReadStatus is like Status, but does not initialize the store, so it never
+// creates or alters tables, e.g. for metrics. It reads the store as it is: a
+// SQLStore whose tables were never created fails and a FileStore reports the
+// state it read on its last Init or Lock.
+func (s *Service) ReadStatus() (StatusReport, error) {
+	return s.ReadStatusContext(context.Background())
+}
+
+// ReadStatusContext is like ReadStatus, but passes ctx to the store.
+func (s *Service) ReadStatusContext(ctx context.Context) (StatusReport, error) {
+	return s.status(ctx)
+}
+
+func (s *Service) status(ctx context.Context) (StatusReport, error) {
+	executed, err := s.findExecuted(ctx)
+	if err != nil {
+		return StatusReport{}, errStore("", err)
+	}
+
+	sums, err := s.findChecksums(ctx)
+	if err != nil {
+		return StatusReport{}, err
+	}
+
+	var records map[string]store.Record
+	if rs, ok := s.store.(RecordStore); ok {
+		if records, err = rs.FindRecords(ctx); err != nil {
+			return StatusReport{}, errStore("", err)
+		}
+	}
+
+	report := StatusReport{Migrations: make([]MigrationStatus, 0, len(s.migrations))}
+	registered := make(map[string]bool, len(s.migrations))
+	for i, myg := range s.migrations {
+		registered[myg.ID] = true
+		at, applied := executed[myg.ID]
+		status := MigrationStatus{
+			ID:       myg.ID,
+			Index:    i,
+			Applied:  applied,
+			Executed: at,
+			Checksum: myg.Checksum,
+			Drifted:  applied && drifted(myg, sums),
+		}
+		if rec, ok := records[myg.ID]; ok && applied {
+			status.Record = &rec
+		}
+		report.Migrations = append(report.Migrations, status)
+	}
+
+	for id := range executed {
+		if !registered[id] {
+			report.Unknown = append(report.Unknown, id)
+		}
+	}
+	sort.Strings(report.Unknown)
+
+	if err := s.recovered(); err != nil {
+		report.Recovered = err.Error()
+	}
+
+	return report, nil
+}
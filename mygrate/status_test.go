@@ -0,0 +1,159 @@
+package mygrate_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lanz-dev/go-mygrate/mygrate"
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+func TestService_Status(t *testing.T) {
+	t.Parallel()
+
+	executed := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	mem := store.NewMemoryStore()
+	if err := mem.Up("1", executed); err != nil {
+		t.Fatal(err)
+	}
+	if err := mem.Up("removed", executed); err != nil {
+		t.Fatal(err)
+	}
+	m := mygrate.New(mygrate.WithStore(mem))
+
+	m.Register("1", nilFunc, nilFunc)
+	m.Register("2", nilFunc, nilFunc)
+
+	report, err := m.Status()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	applied := report.Applied()
+	if len(applied) != 1 || applied[0].ID != "1" || !applied[0].Executed.Equal(executed) {
+		t.Fatalf(`expected '1' to be applied at '%s', got '%v'`, executed, applied)
+	}
+	pending := report.Pending()
+	if len(pending) != 1 || pending[0].ID != "2" || pending[0].Index != 1 {
+		t.Fatalf(`expected '2' to be pending, got '%v'`, pending)
+	}
+	if len(report.Unknown) != 1 || report.Unknown[0] != "removed" {
+		t.Fatalf(`expected 'removed' to be unknown, got '%v'`, report.Unknown)
+	}
+
+	expected := "applied  2021-01-02T03:04:05Z      1\n" +
+		"pending                            2\n" +
+		"unknown                            removed\n"
+	if report.String() != expected {
+		t.Fatalf(`expected report '%s', got '%s'`, expected, report)
+	}
+}
+
+func TestService_Status_WithoutExecutedFinder(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	mock.FindDoneFunc = func() ([]string, error) {
+		return []string{"1"}, nil
+	}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	m.Register("1", nilFunc, nilFunc)
+
+	report, err := m.Status()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if !report.Migrations[0].Applied || !report.Migrations[0].Executed.IsZero() {
+		t.Fatalf(`expected '1' to be applied without executed time, got '%v'`, report.Migrations[0])
+	}
+}
+
+func TestService_Status_FindDoneErr(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	mock.FindDoneFunc = func() ([]string, error) {
+		return nil, errUnitTest
+	}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	_, err := m.Status()
+
+	if !errors.Is(err, errUnitTest) {
+		t.Fatalf(`expected err to be '%s'`, errUnitTest)
+	}
+	if !errors.Is(err, mygrate.ErrStore) {
+		t.Fatalf(`expected err to be '%s'`, mygrate.ErrStore)
+	}
+}
+
+func TestService_Status_Record(t *testing.T) {
+	t.Parallel()
+
+	m := mygrate.New(mygrate.WithStore(store.NewMemoryStore()), mygrate.WithVersion("v1.2.3"))
+	m.Register("1", func() error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}, nilFunc)
+	m.Register("2", nilFunc, nilFunc)
+
+	if _, err := m.Steps(1); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	report, err := m.Status()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	rec := report.Migrations[0].Record
+	if rec == nil {
+		t.Fatal(`expected a record for '1'`)
+	}
+	if rec.Direction != store.DirectionUp || rec.Version != "v1.2.3" || rec.PID != os.Getpid() {
+		t.Fatalf(`expected an up record of 'v1.2.3' by this process, got '%+v'`, rec)
+	}
+	if rec.Duration < 10*time.Millisecond || rec.Started.IsZero() || rec.Executed.Before(rec.Started) {
+		t.Fatalf(`expected the timing of the up func, got '%+v'`, rec)
+	}
+	if report.Migrations[1].Record != nil {
+		t.Fatalf(`expected no record for pending '2', got '%+v'`, report.Migrations[1].Record)
+	}
+}
+
+func TestService_Status_Recovered(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	m := mygrate.New(mygrate.WithStore(store.NewFileStoreWithPath(path)))
+	m.Register("1", nilFunc, nilFunc)
+	m.Register("2", nilFunc, nilFunc)
+	if _, err := m.Migrate(false); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if err := os.WriteFile(path, []byte(`{`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := &logRecorder{}
+	m = mygrate.New(mygrate.WithStore(store.NewFileStoreWithPath(path)), mygrate.WithLogger(logger))
+	report, err := m.Status()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	if !strings.Contains(report.Recovered, "restored from "+path+".bak") {
+		t.Fatalf(`expected the report to describe the recovery, got '%s'`, report.Recovered)
+	}
+	if !strings.Contains(report.String(), "warning: state file") {
+		t.Fatalf(`expected the printed report to warn, got '%s'`, report.String())
+	}
+	if len(logger.msgs) != 1 || logger.msgs[0] != "error store recovered from a corrupt state" {
+		t.Fatalf(`expected the recovery to be logged, got '%v'`, logger.msgs)
+	}
+}
@@ -0,0 +1,117 @@
+package mygrate
+
+import (
+	"context"
+	"time"
+)
+
+// WithTimeout limits the up or down func of the migration to d, a timeout of
+// zero or less disables it. The context passed to the func is canceled once it
+// is exceeded and the run waits for the func to return, then the migration
+// fails with ErrTimeout and the run stops, see WithTimeoutGrace.
+// A transactional migration is rolled back.
+func WithTimeout(d time.Duration) MigrationOption {
+	return func(m *mygration) {
+		m.Timeout = d
+	}
+}
+
+// WithRunTimeout limits the migrations of each run to d in total, a timeout of
+// zero or less disables it. The migration, which exceeds the deadline of the
+// run, fails with ErrTimeout like a migration which exceeds its own timeout,
+// see WithTimeout. The bookkeeping of the store and the lock are not limited.
+// The deadline starts once the store is locked, the rollback and the migrate
+// of Refresh share it.
+func WithRunTimeout(d time.Duration) Option {
+	return func(s *Service) {
+		s.runTimeout = d
+	}
+}
+
+// WithTimeoutGrace limits the wait for a func, which exceeded its timeout, to d.
+// By default the run waits until the func returns. A func which is still
+// running after d fails with ErrStillRunning and the lock is released, so it
+// may still change the database in the background.
+func WithTimeoutGrace(d time.Duration) Option {
+	return func(s *Service) {
+		s.timeoutGrace = d
+	}
+}
+
+type runDeadlineKey struct{}
+
+// runDeadline is the deadline of the migrations of a run, it is passed with the
+// context, so that the runs of Refresh share it.
+type runDeadline struct {
+	at time.Time
+}
+
+// withRunDeadline adds an unset deadline to ctx, unless it already has one.
+func withRunDeadline(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(runDeadlineKey{}).(*runDeadline); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, runDeadlineKey{}, &runDeadline{})
+}
+
+// startRunDeadline sets the deadline of ctx, unless it is already set.
+func (s *Service) startRunDeadline(ctx context.Context) {
+	rd, ok := ctx.Value(runDeadlineKey{}).(*runDeadline)
+	if ok && rd.at.IsZero() && s.runTimeout > 0 {
+		rd.at = time.Now().Add(s.runTimeout)
+	}
+}
+
+// migrationContext limits ctx by timeout and the deadline of the current run.
+func (s *Service) migrationContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	var deadline time.Time
+	if rd, ok := ctx.Value(runDeadlineKey{}).(*runDeadline); ok {
+		deadline = rd.at
+	}
+	if timeout > 0 {
+		if d := time.Now().Add(timeout); deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+	}
+	if deadline.IsZero() {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
+// callTimeout calls fn and returns ErrTimeout if it failed after the deadline
+// of ctx. Once ctx is done, it waits for fn to return, at most for grace if it
+// is greater than zero, otherwise it returns ErrStillRunning.
+func callTimeout(ctx context.Context, grace time.Duration, fn func(context.Context) error) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return fn(ctx)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return ErrTimeout
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- fn(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		var expired <-chan time.Time
+		if grace > 0 {
+			timer := time.NewTimer(grace)
+			defer timer.Stop()
+			expired = timer.C
+		}
+		select {
+		case err = <-done:
+		case <-expired:
+			return ErrStillRunning
+		}
+	}
+
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return ErrTimeout
+	}
+	return err
+}
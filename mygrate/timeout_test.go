@@ -0,0 +1,208 @@
+package mygrate_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lanz-dev/go-mygrate/mygrate"
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+func TestService_WithTimeout(t *testing.T) {
+	t.Parallel()
+
+	mem := store.NewMemoryStore()
+	m := mygrate.New(mygrate.WithStore(mem))
+
+	m.RegisterContext("1", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, nil, mygrate.WithTimeout(10*time.Millisecond))
+	m.Register("2", nilFunc, nil)
+
+	_, err := m.Migrate(false)
+	if !errors.Is(err, mygrate.ErrTimeout) || !errors.Is(err, mygrate.ErrUpFn) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, mygrate.ErrTimeout, err)
+	}
+	var merr *mygrate.Error
+	if !errors.As(err, &merr) || merr.ID != "1" {
+		t.Fatalf(`expected an Error of migration '%s', got '%v'`, "1", err)
+	}
+
+	history, err := m.History()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if len(history) != 1 || history[0].Event != store.EventFailed || history[0].ID != "1" {
+		t.Fatalf(`expected migration '%s' to be marked as failed, got '%+v'`, "1", history)
+	}
+
+	report, err := m.Status()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if applied := len(report.Applied()); applied != 0 {
+		t.Fatalf(`expected no applied migrations, got '%d'`, applied)
+	}
+}
+
+func TestService_WithTimeout_Waits(t *testing.T) {
+	t.Parallel()
+
+	m := mygrate.New(mygrate.WithStore(store.NewMemoryStore()))
+
+	// the func ignores its context, like a query waiting for a table lock
+	canceled := make(chan struct{})
+	release := make(chan struct{})
+	m.RegisterContext("1", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(canceled)
+		<-release
+		return errUnitTest
+	}, nil, mygrate.WithTimeout(10*time.Millisecond))
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := m.Migrate(false)
+		result <- err
+	}()
+
+	<-canceled
+	select {
+	case err := <-result:
+		t.Fatalf(`expected the run to wait for the func, got '%v'`, err)
+	default:
+	}
+	close(release)
+
+	if err := <-result; !errors.Is(err, mygrate.ErrTimeout) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, mygrate.ErrTimeout, err)
+	}
+}
+
+func TestService_WithTimeoutGrace(t *testing.T) {
+	t.Parallel()
+
+	m := mygrate.New(
+		mygrate.WithStore(store.NewMemoryStore()),
+		mygrate.WithTimeoutGrace(10*time.Millisecond),
+	)
+
+	stuck := make(chan struct{})
+	defer close(stuck)
+	m.RegisterContext("1", func(ctx context.Context) error {
+		<-stuck
+		return nil
+	}, nil, mygrate.WithTimeout(10*time.Millisecond))
+
+	_, err := m.Migrate(false)
+	if !errors.Is(err, mygrate.ErrStillRunning) || !errors.Is(err, mygrate.ErrUpFn) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, mygrate.ErrStillRunning, err)
+	}
+
+	history, err := m.History()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if len(history) != 1 || !strings.Contains(history[0].Error, mygrate.ErrStillRunning.Error()) {
+		t.Fatalf(`expected the history to report the running func, got '%+v'`, history)
+	}
+}
+
+func TestService_WithRunTimeout(t *testing.T) {
+	t.Parallel()
+
+	m := mygrate.New(
+		mygrate.WithStore(store.NewMemoryStore()),
+		mygrate.WithRunTimeout(10*time.Millisecond),
+	)
+	// the first migration uses up the deadline of the run, but succeeds
+	m.RegisterContext("1", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}, nil)
+	m.RegisterContext("2", nilContextFunc, nil)
+
+	_, err := m.Migrate(false)
+	if !errors.Is(err, mygrate.ErrTimeout) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, mygrate.ErrTimeout, err)
+	}
+	var merr *mygrate.Error
+	if !errors.As(err, &merr) || merr.ID != "2" {
+		t.Fatalf(`expected an Error of migration '%s', got '%v'`, "2", err)
+	}
+}
+
+func TestService_WithRunTimeout_Refresh(t *testing.T) {
+	t.Parallel()
+
+	m := mygrate.New(
+		mygrate.WithStore(store.NewMemoryStore()),
+		mygrate.WithRunTimeout(time.Hour),
+	)
+	var deadlines []time.Time
+	record := func(ctx context.Context) error {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return errUnitTest
+		}
+		deadlines = append(deadlines, deadline)
+		return nil
+	}
+	m.RegisterContext("1", record, record)
+
+	if _, err := m.Migrate(false); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if err := m.Refresh(); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	if len(deadlines) != 3 {
+		t.Fatalf(`expected 3 deadlines, got '%v'`, deadlines)
+	}
+	// each run has its own deadline, the rollback and the migrate of Refresh share one
+	if !deadlines[1].After(deadlines[0]) || !deadlines[2].Equal(deadlines[1]) {
+		t.Fatalf(`expected a new deadline shared by Refresh, got '%v'`, deadlines)
+	}
+}
+
+func TestService_WithTimeout_Tx(t *testing.T) {
+	t.Parallel()
+
+	db, d := openFakeDB(t)
+	mock := &txMock{db: db}
+	m := mygrate.New(
+		mygrate.WithStore(mock),
+		mygrate.WithTimeoutGrace(10*time.Millisecond),
+	)
+
+	stuck := make(chan struct{})
+	defer close(stuck)
+	m.RegisterTx("1", func(ctx context.Context, tx *sql.Tx) error {
+		<-stuck
+		return nil
+	}, nil, mygrate.WithTimeout(10*time.Millisecond))
+
+	if _, err := m.Migrate(false); !errors.Is(err, mygrate.ErrStillRunning) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, mygrate.ErrStillRunning, err)
+	}
+	if mock.upTxCalls != 0 {
+		t.Fatalf(`expected UpTx not to be called, got '%d' calls`, mock.upTxCalls)
+	}
+
+	// the rollback does not wait for the func
+	for i := 0; i < 100; i++ {
+		if _, rollbacks := d.counts(); rollbacks == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if commits, rollbacks := d.counts(); commits != 0 || rollbacks != 1 {
+		t.Fatalf(`expected 0 commits and 1 rollback, got '%d' and '%d'`, commits, rollbacks)
+	}
+}
@@ -0,0 +1,64 @@
+package mygrate
+
+import (
+	"context"
+)
+
+// Names of the spans started by a Service.
+const (
+	SpanInit      = "mygrate.init"       // SpanInit wraps the init of the store.
+	SpanLock      = "mygrate.lock"       // SpanLock wraps waiting for the lock of the store.
+	SpanFindDone  = "mygrate.find_done"  // SpanFindDone wraps loading the applied migrations.
+	SpanUp        = "mygrate.up"         // SpanUp wraps the up func of a migration.
+	SpanDown      = "mygrate.down"       // SpanDown wraps the down func of a migration.
+	SpanStoreUp   = "mygrate.store.up"   // SpanStoreUp wraps recording an applied migration.
+	SpanStoreDown = "mygrate.store.down" // SpanStoreDown wraps recording a rolled back migration.
+)
+
+// Keys of the span attributes.
+const (
+	AttrID        = "mygrate.id"
+	AttrDirection = "mygrate.direction"
+)
+
+// Attribute is a key/value pair of a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Tracer starts the spans of a Service, see WithTracer and the package tracing.
+type Tracer interface {
+	// Start starts a span, the returned context carries the span to nested spans,
+	// e.g. of the database queries of a migration.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is a started span of a Tracer.
+type Span interface {
+	// End ends the span, err is nil if the operation succeeded.
+	End(err error)
+}
+
+// WithTracer sets a Tracer. Default is no tracing.
+func WithTracer(tracer Tracer) Option {
+	return func(s *Service) {
+		s.tracer = tracer
+	}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) End(error) {}
+
+func (s *Service) startSpan(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	if s.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return s.tracer.Start(ctx, name, attrs...)
+}
+
+// migrationAttrs returns the attributes of the spans of a single migration.
+func migrationAttrs(id string, dir Direction) []Attribute {
+	return []Attribute{{Key: AttrID, Value: id}, {Key: AttrDirection, Value: string(dir)}}
+}
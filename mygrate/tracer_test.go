@@ -0,0 +1,70 @@
+package mygrate_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lanz-dev/go-mygrate/mygrate"
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+// recordingTracer records the ended spans as "<name> <attrs> <err>".
+type recordingTracer struct {
+	spans []string
+}
+
+func (r *recordingTracer) Start(
+	ctx context.Context, name string, attrs ...mygrate.Attribute,
+) (context.Context, mygrate.Span) {
+	return ctx, recordingSpan{r: r, name: name, attrs: attrs}
+}
+
+type recordingSpan struct {
+	r     *recordingTracer
+	name  string
+	attrs []mygrate.Attribute
+}
+
+func (s recordingSpan) End(err error) {
+	var values []string
+	for _, a := range s.attrs {
+		values = append(values, fmt.Sprint(a.Value))
+	}
+	s.r.spans = append(s.r.spans, strings.TrimSpace(fmt.Sprintf("%s %s %v", s.name, strings.Join(values, "/"), err != nil)))
+}
+
+func TestService_WithTracer(t *testing.T) {
+	t.Parallel()
+
+	tracer := &recordingTracer{}
+	m := mygrate.New(mygrate.WithStore(store.NewMemoryStore()), mygrate.WithTracer(tracer))
+	m.Register("1", nilFunc, nilFunc)
+	m.Register("2", errFunc, nilFunc)
+
+	_, err := m.Migrate(false)
+	if !errors.Is(err, errUnitTest) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, errUnitTest, err)
+	}
+	if err := m.Reset(); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	expected := []string{
+		"mygrate.init  false",
+		"mygrate.lock  false",
+		"mygrate.find_done  false",
+		"mygrate.up 1/up false",
+		"mygrate.store.up 1/up false",
+		"mygrate.up 2/up true",
+		"mygrate.lock  false",
+		"mygrate.find_done  false",
+		"mygrate.down 1/down false",
+		"mygrate.store.down 1/down false",
+	}
+	if strings.Join(tracer.spans, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf(`expected spans '%v', got '%v'`, expected, tracer.spans)
+	}
+}
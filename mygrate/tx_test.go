@@ -0,0 +1,248 @@
+package mygrate_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lanz-dev/go-mygrate/mygrate"
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+// fakeDriver is a database/sql driver which only counts commits and rollbacks.
+type fakeDriver struct {
+	mu        sync.Mutex
+	commits   int
+	rollbacks int
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+
+func (d *fakeDriver) counts() (int, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.commits, d.rollbacks
+}
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not implemented") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return &fakeTx{d: c.d}, nil }
+
+type fakeTx struct{ d *fakeDriver }
+
+func (t *fakeTx) Commit() error {
+	t.d.mu.Lock()
+	defer t.d.mu.Unlock()
+	t.d.commits++
+	return nil
+}
+
+func (t *fakeTx) Rollback() error {
+	t.d.mu.Lock()
+	defer t.d.mu.Unlock()
+	t.d.rollbacks++
+	return nil
+}
+
+var (
+	fakeDriverSeq   int
+	fakeDriverSeqMu sync.Mutex
+)
+
+// openFakeDB returns a *sql.DB backed by a new fakeDriver.
+func openFakeDB(t *testing.T) (*sql.DB, *fakeDriver) {
+	t.Helper()
+
+	fakeDriverSeqMu.Lock()
+	fakeDriverSeq++
+	name := fmt.Sprintf("mygrate-fake-%d", fakeDriverSeq)
+	fakeDriverSeqMu.Unlock()
+
+	d := &fakeDriver{}
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+// txMock implements mygrate.Store and mygrate.TxStore.
+type txMock struct {
+	db        *sql.DB
+	done      []string
+	upTxErr   error
+	upTxCalls int
+}
+
+func (m *txMock) Init() error                                  { return nil }
+func (m *txMock) FindDone() ([]string, error)                  { return m.done, nil }
+func (m *txMock) Up(string, time.Time) error                   { return errors.New("Up must not be called") }
+func (m *txMock) Down(string, time.Time) error                 { return errors.New("Down must not be called") }
+func (m *txMock) BeginTx(ctx context.Context) (*sql.Tx, error) { return m.db.BeginTx(ctx, nil) }
+
+func (m *txMock) UpTx(ctx context.Context, tx *sql.Tx, id string, executed time.Time) error {
+	m.upTxCalls++
+	return m.upTxErr
+}
+
+func (m *txMock) DownTx(ctx context.Context, tx *sql.Tx, id string, executed time.Time) error {
+	return nil
+}
+
+// txRecordMock implements mygrate.TxRecordStore and mygrate.ChecksumStore,
+// whose SetChecksum must not be called after a transactional up.
+type txRecordMock struct {
+	*txMock
+	recs []store.Record
+}
+
+func (m *txRecordMock) UpRecordTx(ctx context.Context, tx *sql.Tx, rec store.Record) error {
+	m.recs = append(m.recs, rec)
+	return nil
+}
+
+func (m *txRecordMock) DownRecordTx(ctx context.Context, tx *sql.Tx, rec store.Record) error {
+	return nil
+}
+
+func (m *txRecordMock) SetChecksum(context.Context, string, string) error {
+	return errors.New("SetChecksum must not be called")
+}
+
+func (m *txRecordMock) FindChecksums(context.Context) (map[string]string, error) {
+	return nil, nil
+}
+
+func TestService_RegisterTx_Checksum(t *testing.T) {
+	t.Parallel()
+
+	db, d := openFakeDB(t)
+	mock := &txRecordMock{txMock: &txMock{db: db}}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	m.RegisterTx("1", func(ctx context.Context, tx *sql.Tx) error {
+		return nil
+	}, nil, mygrate.WithChecksum("v1"))
+
+	if _, err := m.Migrate(false); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if len(mock.recs) != 1 || mock.recs[0].Checksum != "v1" {
+		t.Fatalf(`expected the checksum to be recorded in the transaction, got '%+v'`, mock.recs)
+	}
+	if commits, rollbacks := d.counts(); commits != 1 || rollbacks != 0 {
+		t.Fatalf(`expected 1 commit and 0 rollbacks, got '%d' and '%d'`, commits, rollbacks)
+	}
+}
+
+func TestService_RegisterTx_Commit(t *testing.T) {
+	t.Parallel()
+
+	db, d := openFakeDB(t)
+	mock := &txMock{db: db}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	var got *sql.Tx
+	m.RegisterTx(
+		"1",
+		func(ctx context.Context, tx *sql.Tx) error {
+			got = tx
+			return nil
+		},
+		nil,
+	)
+
+	if _, err := m.Migrate(false); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	if got == nil {
+		t.Fatal(`expected migration to receive a transaction`)
+	}
+	if mock.upTxCalls != 1 {
+		t.Fatalf(`expected UpTx to be called '%d' times, got '%d'`, 1, mock.upTxCalls)
+	}
+	if commits, rollbacks := d.counts(); commits != 1 || rollbacks != 0 {
+		t.Fatalf(`expected 1 commit and 0 rollbacks, got '%d' and '%d'`, commits, rollbacks)
+	}
+}
+
+func TestService_RegisterTx_RollbackOnStoreErr(t *testing.T) {
+	t.Parallel()
+
+	db, d := openFakeDB(t)
+	mock := &txMock{db: db, upTxErr: errUnitTest}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	m.RegisterTx(
+		"1",
+		func(ctx context.Context, tx *sql.Tx) error {
+			return nil
+		},
+		nil,
+	)
+
+	_, err := m.Migrate(false)
+
+	if !errors.Is(err, mygrate.ErrStore) {
+		t.Fatalf(`expected err to be '%s'`, mygrate.ErrStore)
+	}
+	if commits, rollbacks := d.counts(); commits != 0 || rollbacks != 1 {
+		t.Fatalf(`expected 0 commits and 1 rollback, got '%d' and '%d'`, commits, rollbacks)
+	}
+}
+
+func TestService_RegisterTx_RollbackOnDownErr(t *testing.T) {
+	t.Parallel()
+
+	db, d := openFakeDB(t)
+	mock := &txMock{db: db, done: []string{"1"}}
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	m.RegisterTx(
+		"1",
+		nil,
+		func(ctx context.Context, tx *sql.Tx) error {
+			return errUnitTest
+		},
+	)
+
+	err := m.Rollback("1")
+
+	if !errors.Is(err, mygrate.ErrDownFn) {
+		t.Fatalf(`expected err to be '%s'`, mygrate.ErrDownFn)
+	}
+	if commits, rollbacks := d.counts(); commits != 0 || rollbacks != 1 {
+		t.Fatalf(`expected 0 commits and 1 rollback, got '%d' and '%d'`, commits, rollbacks)
+	}
+}
+
+func TestService_RegisterTx_Unsupported(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	m := mygrate.New(mygrate.WithStore(mock))
+
+	m.RegisterTx(
+		"1",
+		func(ctx context.Context, tx *sql.Tx) error {
+			return nil
+		},
+		nil,
+	)
+
+	_, err := m.Migrate(false)
+
+	if !errors.Is(err, mygrate.ErrTxUnsupported) {
+		t.Fatalf(`expected err to be '%s', got '%s'`, mygrate.ErrTxUnsupported, err)
+	}
+}
@@ -0,0 +1,88 @@
+package mygrate
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// MaxIDLength is the maximum length of a migration ID, which matches the ID
+// column of the built-in SQL stores.
+const MaxIDLength = 100
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+
+// ValidationError reports all problems of the registered migrations.
+type ValidationError struct {
+	Errors []error // Errors are of type *Error with the InternalErr ErrInvalidMigration.
+}
+
+// Error makes this struct an error.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is implements errors.Is.
+func (e *ValidationError) Is(t error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the registered migrations and reports all problems at once:
+// empty, duplicate or too long IDs, IDs with other characters than letters,
+// digits, '_', '-' and '.', and missing up or down funcs. It returns nil or a
+// *ValidationError.
+//
+// Empty, duplicate and too long IDs are also refused before each run. Other
+// characters are only reported here, as IDs applied by earlier versions may
+// contain them. A missing func fails when it would be executed.
+func (s *Service) Validate() error {
+	errs := s.validateIDs()
+
+	for _, myg := range s.migrations {
+		if myg.ID != "" && len(myg.ID) <= MaxIDLength && !validID.MatchString(myg.ID) {
+			errs = append(errs, errInvalid(myg.ID, "%q contains invalid characters", myg.ID))
+		}
+		if myg.Up == nil && myg.UpTx == nil {
+			errs = append(errs, errInvalid(myg.ID, "%q has no up func", myg.ID))
+		}
+		if myg.Down == nil && myg.DownTx == nil {
+			errs = append(errs, errInvalid(myg.ID, "%q has no down func", myg.ID))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
+
+// validateIDs returns the problems of the IDs, which would break a run.
+func (s *Service) validateIDs() []error {
+	var errs []error
+	seen := make(map[string]bool, len(s.migrations))
+
+	for i, myg := range s.migrations {
+		switch {
+		case myg.ID == "":
+			errs = append(errs, errInvalid(myg.ID, "migration %d has an empty id", i))
+		case len(myg.ID) > MaxIDLength:
+			errs = append(errs, errInvalid(myg.ID, "%q is longer than %d characters", myg.ID, MaxIDLength))
+		}
+
+		if myg.ID != "" && seen[myg.ID] {
+			errs = append(errs, errInvalid(myg.ID, "%q is registered more than once", myg.ID))
+		}
+		seen[myg.ID] = true
+	}
+
+	return errs
+}
@@ -0,0 +1,96 @@
+package mygrate_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lanz-dev/go-mygrate/mygrate"
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+func TestService_Validate(t *testing.T) {
+	t.Parallel()
+
+	m := mygrate.New(mygrate.WithStore(store.NewMemoryStore()))
+	m.Register("1", nilFunc, nilFunc)
+	m.Register("1", nilFunc, nilFunc)
+	m.Register("", nilFunc, nilFunc)
+	m.Register("with space", nilFunc, nilFunc)
+	m.Register(strings.Repeat("a", mygrate.MaxIDLength+1), nilFunc, nilFunc)
+	m.Register("2", nil, nil)
+
+	err := m.Validate()
+
+	var verr *mygrate.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf(`expected err to be a ValidationError, got '%v'`, err)
+	}
+	if len(verr.Errors) != 6 {
+		t.Fatalf(`expected '%d' problems, got '%d': '%s'`, 6, len(verr.Errors), err)
+	}
+	if !errors.Is(err, mygrate.ErrInvalidMigration) {
+		t.Fatalf(`expected err to be '%s'`, mygrate.ErrInvalidMigration)
+	}
+}
+
+func TestService_Validate_Valid(t *testing.T) {
+	t.Parallel()
+
+	m := mygrate.New(mygrate.WithStore(store.NewMemoryStore()))
+	m.Register("0001_init.create", nilFunc, nilFunc)
+	m.Register("do-something", nilFunc, nilFunc)
+
+	if err := m.Validate(); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+}
+
+func TestService_Migrate_DuplicateID(t *testing.T) {
+	t.Parallel()
+
+	mock := buildMock()
+	m := mygrate.New(mygrate.WithStore(mock))
+	m.Register("1", nilFunc, nilFunc)
+	m.Register("1", nilFunc, nilFunc)
+
+	_, err := m.Migrate(false)
+
+	if !errors.Is(err, mygrate.ErrInvalidMigration) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, mygrate.ErrInvalidMigration, err)
+	}
+	if mock.InitCalled || mock.UpCalled {
+		t.Fatal(`expected the store not to be called`)
+	}
+}
+
+func TestService_Migrate_InvalidCharacters(t *testing.T) {
+	t.Parallel()
+
+	// IDs of earlier versions may contain any characters
+	m := mygrate.New(mygrate.WithStore(store.NewMemoryStore()))
+	m.Register("2021/01 create: users", nilFunc, nilFunc)
+
+	if _, err := m.Migrate(false); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if !errors.Is(m.Validate(), mygrate.ErrInvalidMigration) {
+		t.Fatalf(`expected Validate to report '%s'`, mygrate.ErrInvalidMigration)
+	}
+}
+
+func TestService_Migrate_NilFunc(t *testing.T) {
+	t.Parallel()
+
+	m := mygrate.New(mygrate.WithStore(store.NewMemoryStore()))
+	m.Register("1", nil, nilFunc)
+
+	_, err := m.Migrate(false)
+
+	if !errors.Is(err, mygrate.ErrNilFunc) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, mygrate.ErrNilFunc, err)
+	}
+	if !errors.Is(err, mygrate.ErrUpFn) {
+		t.Fatalf(`expected err to be '%s'`, mygrate.ErrUpFn)
+	}
+}
@@ -0,0 +1,285 @@
+// Package scaffold creates new migrations from templates.
+//
+// A Go migration is a file "<prefix>_<name>.go" with a pair of funcs, which
+// is added to the Register func of the migrations package, like in the
+// example folder. A SQL migration is a pair of files "<prefix>_<name>.up.sql"
+// and "<prefix>_<name>.down.sql", as loaded by the package sqlfile.
+package scaffold
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	defaultRegisterFile = "migrations.go"
+	defaultPadding      = 4
+	timestampLayout     = "20060102150405"
+)
+
+var (
+	// ErrInvalidName will be returned if the name can't be used for an ID or file name.
+	ErrInvalidName = errors.New("name has to consist of letters, digits and underscores")
+	// ErrDuplicate will be returned if a migration with the name exists already.
+	ErrDuplicate = errors.New("migration exists already")
+	// ErrNoRegisterFunc will be returned if the register file has no Register func.
+	ErrNoRegisterFunc = errors.New("no func Register found")
+)
+
+var (
+	validName = regexp.MustCompile(`^[a-z0-9_]+$`)
+	prefix    = regexp.MustCompile(`^(\d+)_`)
+)
+
+// Kind is the type of the created migration.
+type Kind int
+
+const (
+	// KindGo creates a Go file with an up and a down func.
+	KindGo Kind = iota
+	// KindSQL creates an up and a down SQL script.
+	KindSQL
+)
+
+// Config describes the migration to create.
+type Config struct {
+	Dir  string // Dir of the migrations.
+	Name string // Name of the migration, e.g. "add_users".
+	Kind Kind
+
+	// Timestamp prefixes the files with the current time instead of a sequential number.
+	Timestamp bool
+	// RegisterFile is the Go file in Dir with the Register func, default is "migrations.go".
+	RegisterFile string
+	// Now returns the current time, default is time.Now.
+	Now func() time.Time
+}
+
+// Create creates the migration and returns the paths of the written files.
+// A Go migration is also added to the Register func of RegisterFile.
+func Create(cfg Config) ([]string, error) {
+	name := normalize(cfg.Name)
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("%q: %w", cfg.Name, ErrInvalidName)
+	}
+	if cfg.RegisterFile == "" {
+		cfg.RegisterFile = defaultRegisterFile
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+
+	entries, err := os.ReadDir(cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		next    uint64 = 1
+		padding        = defaultPadding
+		found   bool
+	)
+	for _, e := range entries {
+		base := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(e.Name(), ".go"), ".up.sql"), ".down.sql")
+		match := prefix.FindStringSubmatch(base)
+		if match == nil {
+			continue
+		}
+		if strings.TrimPrefix(base, match[0]) == name {
+			return nil, fmt.Errorf("%s: %w", e.Name(), ErrDuplicate)
+		}
+		n, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if n >= next {
+			next = n + 1
+		}
+		if !found {
+			// keep the zero padding of the existing files
+			found, padding = true, 0
+		}
+		if len(match[1]) > 1 && match[1][0] == '0' {
+			padding = len(match[1])
+		}
+	}
+
+	pre := fmt.Sprintf("%0*d", padding, next)
+	if cfg.Timestamp {
+		pre = cfg.Now().UTC().Format(timestampLayout)
+	}
+
+	if cfg.Kind == KindSQL {
+		return createSQL(cfg.Dir, pre+"_"+name)
+	}
+	return createGo(cfg, pre, name)
+}
+
+// normalize lowercases name and replaces spaces and dashes with underscores.
+func normalize(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+}
+
+// funcName converts a name like "add_users" to "addUsers".
+func funcName(name string) string {
+	var sb strings.Builder
+	upper := false
+	for _, r := range name {
+		switch {
+		case r == '_':
+			upper = sb.Len() > 0
+		case upper:
+			sb.WriteString(strings.ToUpper(string(r)))
+			upper = false
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	fn := sb.String()
+	if fn == "" || fn[0] >= '0' && fn[0] <= '9' {
+		fn = "m" + fn
+	}
+	return fn
+}
+
+var sqlTemplate = "-- %s\n"
+
+func createSQL(dir string, id string) ([]string, error) {
+	up := filepath.Join(dir, id+".up.sql")
+	down := filepath.Join(dir, id+".down.sql")
+
+	if err := writeNew(up, []byte(fmt.Sprintf(sqlTemplate, "write the up migration here"))); err != nil {
+		return nil, err
+	}
+	if err := writeNew(down, []byte(fmt.Sprintf(sqlTemplate, "write the down migration here"))); err != nil {
+		os.Remove(up)
+		return nil, err
+	}
+
+	return []string{up, down}, nil
+}
+
+var goTemplate = template.Must(template.New("go").Parse(`package {{.Package}}
+
+func {{.Func}}Up() error {
+	return nil
+}
+
+func {{.Func}}Down() error {
+	return nil
+}
+`))
+
+func createGo(cfg Config, pre string, name string) ([]string, error) {
+	registerPath := filepath.Join(cfg.Dir, cfg.RegisterFile)
+	src, err := os.ReadFile(registerPath)
+	if err != nil {
+		return nil, err
+	}
+
+	fn := funcName(name)
+	updated, pkg, err := addRegistration(src, name, fn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", registerPath, err)
+	}
+
+	var buf bytes.Buffer
+	if err := goTemplate.Execute(&buf, struct{ Package, Func string }{pkg, fn}); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(cfg.Dir, pre+"_"+name+".go")
+	if err := writeNew(path, buf.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(registerPath, updated, 0600); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	return []string{path, registerPath}, nil
+}
+
+// addRegistration appends a Register call for id to the func Register of src.
+// It returns the updated source and the package name.
+func addRegistration(src []byte, id string, fn string) ([]byte, string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var register *ast.FuncDecl
+	for _, decl := range file.Decls {
+		if f, ok := decl.(*ast.FuncDecl); ok && f.Name.Name == "Register" && f.Recv == nil && f.Body != nil {
+			register = f
+		}
+	}
+	if register == nil || len(register.Type.Params.List) == 0 || len(register.Type.Params.List[0].Names) == 0 {
+		return nil, "", ErrNoRegisterFunc
+	}
+	registerer := register.Type.Params.List[0].Names[0].Name
+
+	var duplicate bool
+	ast.Inspect(register.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			if v, err := strconv.Unquote(lit.Value); err == nil && v == id {
+				duplicate = true
+			}
+		}
+		return true
+	})
+	if duplicate {
+		return nil, "", fmt.Errorf("%q: %w", id, ErrDuplicate)
+	}
+
+	end := fset.Position(register.Body.Rbrace).Offset
+	line := fmt.Sprintf("\t%s.Register(%q, %sUp, %sDown)\n", registerer, id, fn, fn)
+
+	var buf bytes.Buffer
+	buf.Write(src[:end])
+	buf.WriteString(line)
+	buf.Write(src[end:])
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, "", err
+	}
+
+	return formatted, file.Name.Name, nil
+}
+
+// writeNew writes a new file and fails if it exists already.
+func writeNew(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s: %w", path, ErrDuplicate)
+		}
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
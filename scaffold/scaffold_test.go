@@ -0,0 +1,161 @@
+package scaffold_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lanz-dev/go-mygrate/scaffold"
+)
+
+const registerSrc = `package migrations
+
+import "github.com/lanz-dev/go-mygrate/mygrate"
+
+func Register(r mygrate.Registerer) {
+	r.Register("init_create", initCreateUp, initCreateDown)
+}
+`
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	return string(b)
+}
+
+func TestCreate_Go(t *testing.T) {
+	t.Parallel()
+
+	dir := writeFiles(t, map[string]string{
+		"migrations.go":      registerSrc,
+		"0_init_create.go":   "package migrations\n",
+		"10_do_something.go": "package migrations\n",
+	})
+
+	files, err := scaffold.Create(scaffold.Config{Dir: dir, Name: "Add users"})
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if len(files) != 2 || filepath.Base(files[0]) != "11_add_users.go" {
+		t.Fatalf(`expected file '11_add_users.go', got '%v'`, files)
+	}
+
+	content := readFile(t, files[0])
+	if !strings.Contains(content, "func addUsersUp() error") || !strings.Contains(content, "func addUsersDown() error") {
+		t.Fatalf(`expected up and down funcs, got '%s'`, content)
+	}
+
+	register := readFile(t, filepath.Join(dir, "migrations.go"))
+	expected := "\tr.Register(\"init_create\", initCreateUp, initCreateDown)\n\tr.Register(\"add_users\", addUsersUp, addUsersDown)\n}\n"
+	if !strings.HasSuffix(register, expected) {
+		t.Fatalf(`expected registration '%s', got '%s'`, expected, register)
+	}
+
+	_, err = scaffold.Create(scaffold.Config{Dir: dir, Name: "add_users"})
+	if !errors.Is(err, scaffold.ErrDuplicate) {
+		t.Fatalf(`expected err '%s', got '%v'`, scaffold.ErrDuplicate, err)
+	}
+}
+
+func TestCreate_GoDuplicateRegistration(t *testing.T) {
+	t.Parallel()
+
+	dir := writeFiles(t, map[string]string{"migrations.go": registerSrc})
+
+	_, err := scaffold.Create(scaffold.Config{Dir: dir, Name: "init_create"})
+	if !errors.Is(err, scaffold.ErrDuplicate) {
+		t.Fatalf(`expected err '%s', got '%v'`, scaffold.ErrDuplicate, err)
+	}
+	if register := readFile(t, filepath.Join(dir, "migrations.go")); register != registerSrc {
+		t.Fatalf(`expected unchanged register file, got '%s'`, register)
+	}
+}
+
+func TestCreate_GoNoRegisterFunc(t *testing.T) {
+	t.Parallel()
+
+	dir := writeFiles(t, map[string]string{"migrations.go": "package migrations\n"})
+
+	_, err := scaffold.Create(scaffold.Config{Dir: dir, Name: "add_users"})
+	if !errors.Is(err, scaffold.ErrNoRegisterFunc) {
+		t.Fatalf(`expected err '%s', got '%v'`, scaffold.ErrNoRegisterFunc, err)
+	}
+}
+
+func TestCreate_SQL(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		files     map[string]string
+		timestamp bool
+		expected  string
+	}{
+		"empty dir": {
+			files:    map[string]string{},
+			expected: "0001_add_users",
+		},
+		"zero padded": {
+			files:    map[string]string{"007_init.up.sql": "", "007_init.down.sql": ""},
+			expected: "008_add_users",
+		},
+		"timestamp": {
+			files:     map[string]string{"0001_init.up.sql": ""},
+			timestamp: true,
+			expected:  "20210102030405_add_users",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := writeFiles(t, tt.files)
+			files, err := scaffold.Create(scaffold.Config{
+				Dir:       dir,
+				Name:      "add-users",
+				Kind:      scaffold.KindSQL,
+				Timestamp: tt.timestamp,
+				Now: func() time.Time {
+					return time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+				},
+			})
+			if err != nil {
+				t.Fatalf(`did not expected err '%s'`, err)
+			}
+
+			expected := []string{tt.expected + ".up.sql", tt.expected + ".down.sql"}
+			if len(files) != 2 || filepath.Base(files[0]) != expected[0] || filepath.Base(files[1]) != expected[1] {
+				t.Fatalf(`expected files '%v', got '%v'`, expected, files)
+			}
+		})
+	}
+}
+
+func TestCreate_InvalidName(t *testing.T) {
+	t.Parallel()
+
+	_, err := scaffold.Create(scaffold.Config{Dir: t.TempDir(), Name: "add users!"})
+	if !errors.Is(err, scaffold.ErrInvalidName) {
+		t.Fatalf(`expected err '%s', got '%v'`, scaffold.ErrInvalidName, err)
+	}
+}
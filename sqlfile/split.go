@@ -0,0 +1,331 @@
+package sqlfile
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+const delimiterDirective = "DELIMITER"
+
+var (
+	// ErrUnterminated will be returned if a string, identifier, comment or dollar quote is not closed.
+	ErrUnterminated = errors.New("unterminated")
+	// ErrEmptyDelimiter will be returned if a DELIMITER directive sets no delimiter.
+	ErrEmptyDelimiter = errors.New("empty delimiter")
+)
+
+// Syntax describes the dialect specific parts of a script, which matter for splitting it.
+//
+// Independent of the syntax, a line "DELIMITER <delimiter>" changes the
+// delimiter for the following statements, e.g. to "//" for stored procedures.
+type Syntax struct {
+	// Delimiter ends a statement, default is ";".
+	Delimiter string
+	// BatchSeparator is a line which ends a statement, e.g. "GO" for SQL Server.
+	// If set, statements are only split at the batch separator.
+	BatchSeparator string
+
+	BackslashEscapes bool // BackslashEscapes in string literals, e.g. 'it\'s', like MySQL.
+	DollarQuotes     bool // DollarQuotes like $body$ ... $body$ and escape strings like E'it\'s' of PostgreSQL.
+	NestedComments   bool // NestedComments like /* /* */ */ of PostgreSQL.
+	HashComments     bool // HashComments like # comment of MySQL.
+	Backticks        bool // Backticks quote identifiers, like MySQL and SQLite.
+	Brackets         bool // Brackets quote identifiers, like SQL Server and SQLite.
+}
+
+// SyntaxFor returns the Syntax of the built-in dialects of package store.
+// Unknown dialects get the syntax of standard SQL.
+func SyntaxFor(d store.Dialect) Syntax {
+	switch d {
+	case store.DialectPostgres:
+		return Syntax{DollarQuotes: true, NestedComments: true}
+	case store.DialectMySQL:
+		return Syntax{BackslashEscapes: true, HashComments: true, Backticks: true}
+	case store.DialectSQLite:
+		return Syntax{Backticks: true, Brackets: true}
+	case store.DialectSQLServer:
+		return Syntax{BatchSeparator: "GO", Brackets: true}
+	}
+	return Syntax{}
+}
+
+// Statement is a single statement of a script.
+type Statement struct {
+	SQL   string
+	Index int // Index of the statement in the script, starting at 0.
+	Line  int // Line in the script where the statement starts, starting at 1.
+}
+
+// SyntaxError describes where a script could not be split.
+type SyntaxError struct {
+	Line int
+	Err  error
+}
+
+// Error makes this struct an error.
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+// Unwrap implements errors.Unwrap.
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// StatementError describes which statement of a script failed.
+type StatementError struct {
+	Index int // Index of the statement in the script, starting at 0.
+	Line  int // Line in the script where the statement starts, starting at 1.
+	Err   error
+}
+
+// Error makes this struct an error.
+func (e *StatementError) Error() string {
+	return fmt.Sprintf("statement %d at line %d: %s", e.Index, e.Line, e.Err)
+}
+
+// Unwrap implements errors.Unwrap.
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
+
+// splitter holds the state while splitting a script.
+type splitter struct {
+	syntax Syntax
+	script string
+	stmts  []Statement
+
+	pos   int  // pos is the current byte offset.
+	line  int  // line of pos.
+	start int  // start of the current statement.
+	code  int  // code is the line where the current statement has its first code, 0 if none.
+	bol   bool // bol is true at the beginning of a line.
+}
+
+// Split splits script into its statements. Statements without code,
+// e.g. only comments, are dropped.
+func Split(script string, syntax Syntax) ([]Statement, error) {
+	if syntax.Delimiter == "" {
+		syntax.Delimiter = ";"
+	}
+	sp := &splitter{syntax: syntax, script: script, line: 1, bol: true}
+	if err := sp.split(); err != nil {
+		return nil, err
+	}
+	return sp.stmts, nil
+}
+
+func (sp *splitter) split() error {
+	s := sp.script
+	for sp.pos < len(s) {
+		if sp.bol {
+			sp.bol = false
+			if ok, err := sp.directive(); ok || err != nil {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		c := s[sp.pos]
+		rest := s[sp.pos:]
+		var err error
+		switch {
+		case c == '\n':
+			sp.line++
+			sp.bol = true
+			sp.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			sp.pos++
+		case strings.HasPrefix(rest, "--") || (c == '#' && sp.syntax.HashComments):
+			sp.skipLine()
+		case strings.HasPrefix(rest, "/*"):
+			err = sp.skipBlockComment()
+		case c == '\'':
+			err = sp.skipQuoted('\'', sp.syntax.BackslashEscapes || sp.escapeString())
+		case c == '"':
+			err = sp.skipQuoted('"', false)
+		case c == '`' && sp.syntax.Backticks:
+			err = sp.skipQuoted('`', false)
+		case c == '[' && sp.syntax.Brackets:
+			err = sp.skipQuoted(']', false)
+		case c == '$' && sp.syntax.DollarQuotes && !isIdentByte(sp.prev(1)) && sp.dollarTag() != "":
+			err = sp.skipDollarQuoted()
+		case sp.syntax.BatchSeparator == "" && strings.HasPrefix(rest, sp.syntax.Delimiter):
+			sp.flush(sp.pos)
+			sp.pos += len(sp.syntax.Delimiter)
+			sp.start = sp.pos
+		default:
+			sp.markCode()
+			sp.pos++
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	sp.flush(len(s))
+	return nil
+}
+
+// directive handles a DELIMITER or batch separator line at pos.
+func (sp *splitter) directive() (bool, error) {
+	end := strings.IndexByte(sp.script[sp.pos:], '\n')
+	if end < 0 {
+		end = len(sp.script)
+	} else {
+		end += sp.pos
+	}
+	line := strings.TrimSpace(sp.script[sp.pos:end])
+
+	isDelimiter := len(line) >= len(delimiterDirective) &&
+		strings.EqualFold(line[:len(delimiterDirective)], delimiterDirective) &&
+		(len(line) == len(delimiterDirective) || line[len(delimiterDirective)] == ' ' || line[len(delimiterDirective)] == '\t')
+	isBatch := sp.syntax.BatchSeparator != "" && strings.EqualFold(line, sp.syntax.BatchSeparator)
+	if !isDelimiter && !isBatch {
+		return false, nil
+	}
+
+	if isDelimiter {
+		delimiter := strings.TrimSpace(line[len(delimiterDirective):])
+		if delimiter == "" {
+			return false, &SyntaxError{Line: sp.line, Err: ErrEmptyDelimiter}
+		}
+		sp.syntax.Delimiter = delimiter
+	}
+
+	sp.flush(sp.pos)
+	sp.pos = end
+	sp.start = end
+	return true, nil
+}
+
+func (sp *splitter) markCode() {
+	if sp.code == 0 {
+		sp.code = sp.line
+	}
+}
+
+func (sp *splitter) flush(end int) {
+	if sp.code != 0 {
+		sp.stmts = append(sp.stmts, Statement{
+			SQL:   strings.TrimSpace(sp.script[sp.start:end]),
+			Index: len(sp.stmts),
+			Line:  sp.code,
+		})
+	}
+	sp.code = 0
+	sp.start = end
+}
+
+func (sp *splitter) skipLine() {
+	end := strings.IndexByte(sp.script[sp.pos:], '\n')
+	if end < 0 {
+		sp.pos = len(sp.script)
+		return
+	}
+	sp.pos += end
+}
+
+// advance moves pos by n bytes and counts the skipped lines.
+func (sp *splitter) advance(n int) {
+	sp.line += strings.Count(sp.script[sp.pos:sp.pos+n], "\n")
+	sp.pos += n
+}
+
+func (sp *splitter) unterminated(what string) error {
+	return &SyntaxError{Line: sp.line, Err: fmt.Errorf("%w %s", ErrUnterminated, what)}
+}
+
+func (sp *splitter) skipBlockComment() error {
+	s := sp.script
+	depth := 0
+	for i := sp.pos; i < len(s)-1; i++ {
+		switch {
+		case s[i] == '/' && s[i+1] == '*':
+			if depth == 0 || sp.syntax.NestedComments {
+				depth++
+			}
+			i++
+		case s[i] == '*' && s[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				sp.advance(i + 1 - sp.pos)
+				return nil
+			}
+		}
+	}
+	return sp.unterminated("comment")
+}
+
+// skipQuoted skips a literal or identifier, a doubled closing quote is an escaped quote.
+func (sp *splitter) skipQuoted(closing byte, backslash bool) error {
+	sp.markCode()
+	s := sp.script
+	for i := sp.pos + 1; i < len(s); i++ {
+		switch {
+		case backslash && s[i] == '\\':
+			i++
+		case s[i] == closing && i+1 < len(s) && s[i+1] == closing:
+			i++
+		case s[i] == closing:
+			sp.advance(i + 1 - sp.pos)
+			return nil
+		}
+	}
+	return sp.unterminated("quote " + string(s[sp.pos]))
+}
+
+// prev returns the n-th byte before pos, or 0 if there is none.
+func (sp *splitter) prev(n int) byte {
+	if sp.pos < n {
+		return 0
+	}
+	return sp.script[sp.pos-n]
+}
+
+// isIdentByte reports if c can be part of an unquoted identifier, e.g. a$b of PostgreSQL.
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// escapeString reports if the literal at pos is a PostgreSQL escape string like E'it\'s'.
+func (sp *splitter) escapeString() bool {
+	e := sp.prev(1)
+	return sp.syntax.DollarQuotes && (e == 'E' || e == 'e') && !isIdentByte(sp.prev(2))
+}
+
+// dollarTag returns the dollar quote tag at pos like $body$, or "" if there is none.
+// Placeholders like $1 are no tags.
+func (sp *splitter) dollarTag() string {
+	s := sp.script
+	for i := sp.pos + 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[sp.pos : i+1]
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		case c >= '0' && c <= '9' && i > sp.pos+1:
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+func (sp *splitter) skipDollarQuoted() error {
+	sp.markCode()
+	tag := sp.dollarTag()
+	end := strings.Index(sp.script[sp.pos+len(tag):], tag)
+	if end < 0 {
+		return sp.unterminated("dollar quote " + tag)
+	}
+	sp.advance(len(tag) + end + len(tag))
+	return nil
+}
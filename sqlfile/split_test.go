@@ -0,0 +1,148 @@
+package sqlfile_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/lanz-dev/go-mygrate/sqlfile"
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		script   string
+		syntax   sqlfile.Syntax
+		expected []sqlfile.Statement
+	}{
+		"statements and comments": {
+			script: "-- create\nCREATE TABLE a (id INT);\n/* ; */\nINSERT INTO a VALUES (1); -- done;\n",
+			expected: []sqlfile.Statement{
+				{SQL: "-- create\nCREATE TABLE a (id INT)", Index: 0, Line: 2},
+				{SQL: "/* ; */\nINSERT INTO a VALUES (1)", Index: 1, Line: 4},
+			},
+		},
+		"string literals": {
+			script: "INSERT INTO a VALUES ('a;b', 'it''s;');\nSELECT \"x;y\" FROM a",
+			expected: []sqlfile.Statement{
+				{SQL: "INSERT INTO a VALUES ('a;b', 'it''s;')", Index: 0, Line: 1},
+				{SQL: "SELECT \"x;y\" FROM a", Index: 1, Line: 2},
+			},
+		},
+		"backslash escapes": {
+			script: `INSERT INTO a VALUES ('it\'s;'); SELECT 1`,
+			syntax: sqlfile.SyntaxFor(store.DialectMySQL),
+			expected: []sqlfile.Statement{
+				{SQL: `INSERT INTO a VALUES ('it\'s;')`, Index: 0, Line: 1},
+				{SQL: "SELECT 1", Index: 1, Line: 1},
+			},
+		},
+		"dollar quotes": {
+			script: "CREATE FUNCTION f() RETURNS INT AS $body$\nBEGIN\n  RETURN 1;\nEND;\n$body$ LANGUAGE plpgsql;\nSELECT $1;",
+			syntax: sqlfile.SyntaxFor(store.DialectPostgres),
+			expected: []sqlfile.Statement{
+				{SQL: "CREATE FUNCTION f() RETURNS INT AS $body$\nBEGIN\n  RETURN 1;\nEND;\n$body$ LANGUAGE plpgsql", Index: 0, Line: 1},
+				{SQL: "SELECT $1", Index: 1, Line: 6},
+			},
+		},
+		"dollar in identifier": {
+			script: "SELECT a$b$c FROM t; SELECT 2",
+			syntax: sqlfile.SyntaxFor(store.DialectPostgres),
+			expected: []sqlfile.Statement{
+				{SQL: "SELECT a$b$c FROM t", Index: 0, Line: 1},
+				{SQL: "SELECT 2", Index: 1, Line: 1},
+			},
+		},
+		"escape strings": {
+			script: `SELECT E'it\'s;', e'\\'; SELECT 'a\'; SELECT 2`,
+			syntax: sqlfile.SyntaxFor(store.DialectPostgres),
+			expected: []sqlfile.Statement{
+				{SQL: `SELECT E'it\'s;', e'\\'`, Index: 0, Line: 1},
+				{SQL: `SELECT 'a\'`, Index: 1, Line: 1},
+				{SQL: "SELECT 2", Index: 2, Line: 1},
+			},
+		},
+		"custom delimiter": {
+			script: "DELIMITER //\nCREATE PROCEDURE p() BEGIN SELECT 1; END//\nDELIMITER ;\nCALL p();",
+			syntax: sqlfile.SyntaxFor(store.DialectMySQL),
+			expected: []sqlfile.Statement{
+				{SQL: "CREATE PROCEDURE p() BEGIN SELECT 1; END", Index: 0, Line: 2},
+				{SQL: "CALL p()", Index: 1, Line: 4},
+			},
+		},
+		"batch separator": {
+			script: "CREATE TABLE a (id INT);\nINSERT INTO a VALUES (1);\ngo\nSELECT 1",
+			syntax: sqlfile.SyntaxFor(store.DialectSQLServer),
+			expected: []sqlfile.Statement{
+				{SQL: "CREATE TABLE a (id INT);\nINSERT INTO a VALUES (1);", Index: 0, Line: 1},
+				{SQL: "SELECT 1", Index: 1, Line: 4},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			stmts, err := sqlfile.Split(tt.script, tt.syntax)
+			if err != nil {
+				t.Fatalf(`did not expected err '%s'`, err)
+			}
+			if len(stmts) != len(tt.expected) {
+				t.Fatalf(`expected '%d' statements, got '%#v'`, len(tt.expected), stmts)
+			}
+			for i := range stmts {
+				if stmts[i] != tt.expected[i] {
+					t.Fatalf(`expected statement '%#v', got '%#v'`, tt.expected[i], stmts[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSplit_Unterminated(t *testing.T) {
+	t.Parallel()
+
+	_, err := sqlfile.Split("SELECT 1;\nSELECT 'a;\n", sqlfile.Syntax{})
+
+	var syntaxErr *sqlfile.SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Line != 2 {
+		t.Fatalf(`expected a syntax error in line 2, got '%v'`, err)
+	}
+	if !errors.Is(err, sqlfile.ErrUnterminated) {
+		t.Fatalf(`expected err to be '%s'`, sqlfile.ErrUnterminated)
+	}
+}
+
+// failingExecer fails on the n-th statement.
+type failingExecer struct {
+	n int
+}
+
+func (e *failingExecer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	e.n--
+	if e.n == 0 {
+		return nil, errUnitTest
+	}
+	return nil, nil
+}
+
+var errUnitTest = errors.New("unittest")
+
+func TestExec_StatementError(t *testing.T) {
+	t.Parallel()
+
+	err := sqlfile.Exec(context.Background(), &failingExecer{n: 2}, "SELECT 1;\n\nSELECT 2;", sqlfile.Syntax{})
+
+	var stmtErr *sqlfile.StatementError
+	if !errors.As(err, &stmtErr) || stmtErr.Index != 1 || stmtErr.Line != 3 {
+		t.Fatalf(`expected statement 1 at line 3 to fail, got '%v'`, err)
+	}
+	if !errors.Is(err, errUnitTest) {
+		t.Fatalf(`expected err to be '%s'`, errUnitTest)
+	}
+}
@@ -0,0 +1,263 @@
+// Package sqlfile loads plain SQL migrations from a fs.FS, e.g. an embed.FS,
+// and registers them on a mygrate.Registerer.
+//
+// A migration consists of two files following the naming convention
+// "<version>_<name>.up.sql" and "<version>_<name>.down.sql", e.g.
+// "0003_add_users.up.sql". The ID of the migration is "<version>_<name>"
+// and migrations are registered in the numeric order of their version.
+//
+// A script which contains the line "-- mygrate:no-transaction" is never
+// executed inside a transaction, e.g. for CREATE INDEX CONCURRENTLY.
+package sqlfile
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lanz-dev/go-mygrate/mygrate"
+)
+
+const noTxDirective = "-- mygrate:no-transaction"
+
+var (
+	// ErrInvalidName will be returned if a .sql file does not follow the naming convention.
+	ErrInvalidName = errors.New("file name does not match <version>_<name>.(up|down).sql")
+	// ErrDuplicateVersion will be returned if two migrations have the same version.
+	ErrDuplicateVersion = errors.New("duplicate version")
+	// ErrMissingScript will be returned if the up or down script of a migration is missing.
+	ErrMissingScript = errors.New("missing up or down script")
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Execer is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// TxRegisterer can register transactional and non transactional migrations, e.g. *mygrate.Service.
+type TxRegisterer interface {
+	mygrate.ContextRegisterer
+	mygrate.TxRegisterer
+}
+
+// Migration is a pair of SQL scripts.
+type Migration struct {
+	Version uint64
+	ID      string
+	Up      string
+	Down    string
+	// NoTx is set if one of the scripts contains the no-transaction directive.
+	NoTx bool
+	// Checksum is the hex encoded SHA-256 of the up and the down script,
+	// separated by a NUL byte. It is registered with mygrate.WithChecksum to
+	// detect changes of applied migrations.
+	Checksum string
+}
+
+// Load reads all migrations from dir of fsys, ordered by version.
+// Files without the suffix ".sql" and sub directories are ignored.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint64]*Migration{}
+	scripts := map[string]int{} // number of scripts by ID
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+
+		match := fileName.FindStringSubmatch(e.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), ErrInvalidName)
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		id := match[1] + "_" + match[2]
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, ID: id}
+			byVersion[version] = m
+		}
+		if m.ID != id {
+			return nil, fmt.Errorf("%s and %s: %w %d", m.ID, id, ErrDuplicateVersion, version)
+		}
+
+		buf, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		scripts[id]++
+		script := string(buf)
+		if hasNoTxDirective(script) {
+			m.NoTx = true
+		}
+		if match[3] == "up" {
+			m.Up = script
+		} else {
+			m.Down = script
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if scripts[m.ID] != 2 {
+			return nil, fmt.Errorf("%s: %w", m.ID, ErrMissingScript)
+		}
+		m.Checksum = checksum(m.Up, m.Down)
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// checksum hashes both scripts, as the down script of an applied migration
+// runs on rollback. The separator keeps "ab"+"c" apart from "a"+"bc".
+func checksum(up, down string) string {
+	h := sha256.New()
+	_, _ = io.WriteString(h, up)
+	_, _ = h.Write([]byte{0})
+	_, _ = io.WriteString(h, down)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func hasNoTxDirective(script string) bool {
+	for _, line := range strings.Split(script, "\n") {
+		if strings.TrimSpace(line) == noTxDirective {
+			return true
+		}
+	}
+	return false
+}
+
+// Option configures Register and RegisterTx.
+type Option func(o *options)
+
+type options struct {
+	syntax Syntax
+}
+
+// WithSyntax sets the Syntax which is used to split the scripts into statements,
+// e.g. WithSyntax(SyntaxFor(store.DialectPostgres)). Default is standard SQL.
+func WithSyntax(syntax Syntax) Option {
+	return func(o *options) {
+		o.syntax = syntax
+	}
+}
+
+// split splits the scripts of m, so syntax errors are found on registration.
+func split(m Migration, opts []Option) ([]Statement, []Statement, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	up, err := Split(m.Up, o.syntax)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s.up.sql: %w", m.ID, err)
+	}
+	down, err := Split(m.Down, o.syntax)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s.down.sql: %w", m.ID, err)
+	}
+
+	return up, down, nil
+}
+
+// Register loads the migrations from dir of fsys and registers them on r.
+// The statements are executed one by one against db, which may be a *sql.DB
+// or a transaction managed by the caller.
+func Register(r mygrate.ContextRegisterer, fsys fs.FS, dir string, db Execer, opts ...Option) error {
+	migrations, err := Load(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		up, down, err := split(m, opts)
+		if err != nil {
+			return err
+		}
+		r.RegisterContext(m.ID, execFunc(db, up), execFunc(db, down), mygrate.WithChecksum(m.Checksum))
+	}
+
+	return nil
+}
+
+// RegisterTx loads the migrations from dir of fsys and registers them on r,
+// so they run inside the transaction of a mygrate.TxStore together with its bookkeeping.
+// Migrations with the no-transaction directive are executed against db instead.
+func RegisterTx(r TxRegisterer, fsys fs.FS, dir string, db *sql.DB, opts ...Option) error {
+	migrations, err := Load(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		up, down, err := split(m, opts)
+		if err != nil {
+			return err
+		}
+		checksum := mygrate.WithChecksum(m.Checksum)
+		if m.NoTx {
+			r.RegisterContext(m.ID, execFunc(db, up), execFunc(db, down), checksum)
+			continue
+		}
+		r.RegisterTx(m.ID, execTxFunc(up), execTxFunc(down), checksum)
+	}
+
+	return nil
+}
+
+// Exec splits script with syntax and executes the statements one by one against db.
+// If a statement fails, a *StatementError is returned.
+func Exec(ctx context.Context, db Execer, script string, syntax Syntax) error {
+	stmts, err := Split(script, syntax)
+	if err != nil {
+		return err
+	}
+
+	return execStatements(ctx, db, stmts)
+}
+
+func execStatements(ctx context.Context, db Execer, stmts []Statement) error {
+	for _, stmt := range stmts {
+		if _, err := db.ExecContext(ctx, stmt.SQL); err != nil {
+			return &StatementError{Index: stmt.Index, Line: stmt.Line, Err: err}
+		}
+	}
+
+	return nil
+}
+
+func execFunc(db Execer, stmts []Statement) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return execStatements(ctx, db, stmts)
+	}
+}
+
+func execTxFunc(stmts []Statement) func(context.Context, *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		return execStatements(ctx, tx, stmts)
+	}
+}
@@ -0,0 +1,210 @@
+package sqlfile_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/lanz-dev/go-mygrate/mygrate"
+	"github.com/lanz-dev/go-mygrate/sqlfile"
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+// registerer records registered migrations.
+type registerer struct {
+	ids  []string
+	up   map[string]func(context.Context) error
+	upTx map[string]func(context.Context, *sql.Tx) error
+}
+
+func newRegisterer() *registerer {
+	return &registerer{
+		up:   map[string]func(context.Context) error{},
+		upTx: map[string]func(context.Context, *sql.Tx) error{},
+	}
+}
+
+func (r *registerer) RegisterContext(
+	id string,
+	up func(context.Context) error,
+	down func(context.Context) error,
+	opts ...mygrate.MigrationOption,
+) {
+	r.ids = append(r.ids, id)
+	r.up[id] = up
+}
+
+func (r *registerer) RegisterTx(
+	id string,
+	up func(context.Context, *sql.Tx) error,
+	down func(context.Context, *sql.Tx) error,
+	opts ...mygrate.MigrationOption,
+) {
+	r.ids = append(r.ids, id)
+	r.upTx[id] = up
+}
+
+// execer records executed statements.
+type execer struct {
+	queries []string
+}
+
+func (e *execer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	e.queries = append(e.queries, query)
+	return nil, nil
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"migrations/10_second.up.sql":   {Data: []byte("CREATE TABLE b (id INT);")},
+		"migrations/10_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"migrations/2_first.up.sql":     {Data: []byte("CREATE TABLE a (id INT);")},
+		"migrations/2_first.down.sql":   {Data: []byte("")},
+		"migrations/README.md":          {Data: []byte("ignored")},
+	}
+
+	migrations, err := sqlfile.Load(fsys, "migrations")
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	if len(migrations) != 2 || migrations[0].ID != "2_first" || migrations[1].ID != "10_second" {
+		t.Fatalf(`expected migrations to be ordered by version, got '%v'`, migrations)
+	}
+	if migrations[1].Down != "DROP TABLE b;" {
+		t.Fatalf(`expected down script 'DROP TABLE b;', got '%s'`, migrations[1].Down)
+	}
+	// sha256 of "CREATE TABLE a (id INT);\x00", the down script is empty
+	expected := "6bda73c4d05234689a142931978c54e3efdc33982e317fee4b2421da844f546f"
+	if migrations[0].Checksum != expected {
+		t.Fatalf(`expected checksum '%s', got '%s'`, expected, migrations[0].Checksum)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		fsys fstest.MapFS
+		err  error
+	}{
+		"invalid name": {
+			fsys: fstest.MapFS{"1_first.sql": {}},
+			err:  sqlfile.ErrInvalidName,
+		},
+		"duplicate version": {
+			fsys: fstest.MapFS{"1_first.up.sql": {}, "01_other.down.sql": {}},
+			err:  sqlfile.ErrDuplicateVersion,
+		},
+		"missing down": {
+			fsys: fstest.MapFS{"1_first.up.sql": {}},
+			err:  sqlfile.ErrMissingScript,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := sqlfile.Load(tt.fsys, ".")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf(`expected err to be '%s', got '%v'`, tt.err, err)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"1_first.up.sql":   {Data: []byte("CREATE TABLE a (id INT);")},
+		"1_first.down.sql": {Data: []byte("DROP TABLE a;")},
+	}
+	r := newRegisterer()
+	db := &execer{}
+
+	if err := sqlfile.Register(r, fsys, ".", db); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if err := r.up["1_first"](context.Background()); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	if len(db.queries) != 1 || db.queries[0] != "CREATE TABLE a (id INT)" {
+		t.Fatalf(`expected up script to be executed, got '%v'`, db.queries)
+	}
+}
+
+func TestRegisterTx_NoTxDirective(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"1_table.up.sql":   {Data: []byte("CREATE TABLE a (id INT);")},
+		"1_table.down.sql": {Data: []byte("DROP TABLE a;")},
+		"2_index.up.sql":   {Data: []byte("-- mygrate:no-transaction\nCREATE INDEX CONCURRENTLY i ON a (id);")},
+		"2_index.down.sql": {Data: []byte("DROP INDEX i;")},
+	}
+	r := newRegisterer()
+
+	if err := sqlfile.RegisterTx(r, fsys, ".", nil); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	if _, ok := r.upTx["1_table"]; !ok {
+		t.Fatal(`expected '1_table' to be registered transactional`)
+	}
+	if _, ok := r.up["2_index"]; !ok {
+		t.Fatal(`expected '2_index' to be registered without transaction`)
+	}
+}
+
+func TestRegister_ChecksumDrift(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		file   string
+		script string
+	}{
+		"up":   {file: "1_first.up.sql", script: "CREATE TABLE a (id BIGINT);"},
+		"down": {file: "1_first.down.sql", script: "DROP TABLE IF EXISTS a;"},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			mem := store.NewMemoryStore()
+			db := &execer{}
+
+			m := mygrate.New(mygrate.WithStore(mem))
+			fsys := fstest.MapFS{
+				"1_first.up.sql":   {Data: []byte("CREATE TABLE a (id INT);")},
+				"1_first.down.sql": {Data: []byte("DROP TABLE a;")},
+			}
+			if err := sqlfile.Register(m, fsys, ".", db); err != nil {
+				t.Fatalf(`did not expected err '%s'`, err)
+			}
+			if _, err := m.Migrate(false); err != nil {
+				t.Fatalf(`did not expected err '%s'`, err)
+			}
+
+			// the applied script was edited afterwards
+			m = mygrate.New(mygrate.WithStore(mem))
+			fsys[tt.file] = &fstest.MapFile{Data: []byte(tt.script)}
+			if err := sqlfile.Register(m, fsys, ".", db); err != nil {
+				t.Fatalf(`did not expected err '%s'`, err)
+			}
+
+			if _, err := m.Migrate(false); !errors.Is(err, mygrate.ErrChecksumMismatch) {
+				t.Fatalf(`expected err to be '%s', got '%v'`, mygrate.ErrChecksumMismatch, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// AdvisoryLocker acquires a database specific session lock on a dedicated connection.
+type AdvisoryLocker interface {
+	// TryLock tries to acquire the lock without waiting and reports if it was acquired.
+	TryLock(ctx context.Context, conn *sql.Conn) (bool, error)
+	// Unlock releases the lock.
+	Unlock(ctx context.Context, conn *sql.Conn) error
+}
+
+// queryAdvisoryLock implements AdvisoryLocker with a query which selects 1 if
+// the lock was acquired and 0 if not.
+type queryAdvisoryLock struct {
+	tryLock string
+	unlock  string
+	key     interface{}
+}
+
+// TryLock implements AdvisoryLocker.
+func (l queryAdvisoryLock) TryLock(ctx context.Context, conn *sql.Conn) (bool, error) {
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, l.tryLock, l.key).Scan(&acquired); err != nil {
+		return false, err
+	}
+	if !acquired.Valid {
+		return false, errors.New("advisory lock returned NULL")
+	}
+	return acquired.Int64 == 1, nil
+}
+
+// Unlock implements AdvisoryLocker.
+func (l queryAdvisoryLock) Unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, l.unlock, l.key)
+	return err
+}
+
+// PostgresAdvisoryLock returns an AdvisoryLocker using pg_try_advisory_lock.
+func PostgresAdvisoryLock(key int64) AdvisoryLocker {
+	return queryAdvisoryLock{
+		tryLock: `SELECT CASE WHEN pg_try_advisory_lock($1) THEN 1 ELSE 0 END`,
+		unlock:  `SELECT pg_advisory_unlock($1)`,
+		key:     key,
+	}
+}
+
+// MySQLAdvisoryLock returns an AdvisoryLocker using GET_LOCK.
+func MySQLAdvisoryLock(name string) AdvisoryLocker {
+	return queryAdvisoryLock{
+		tryLock: `SELECT GET_LOCK(?, 0)`,
+		unlock:  `SELECT RELEASE_LOCK(?)`,
+		key:     name,
+	}
+}
+
+// SQLServerAdvisoryLock returns an AdvisoryLocker using sp_getapplock.
+func SQLServerAdvisoryLock(resource string) AdvisoryLocker {
+	return queryAdvisoryLock{
+		tryLock: `DECLARE @r INT;
+			EXEC @r = sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = 0;
+			SELECT CASE WHEN @r >= 0 THEN 1 ELSE 0 END`,
+		unlock: `EXEC sp_releaseapplock @Resource = @p1, @LockOwner = 'Session'`,
+		key:    resource,
+	}
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// ColumnType is a database independent type of a column.
+type ColumnType int
+
+const (
+	// TypeID is a short string, which can be used as primary key.
+	TypeID ColumnType = iota
+	// TypeString is a string of up to 255 characters.
+	TypeString
+	// TypeText is a string without a length limit.
+	TypeText
+	// TypeInt is a 32 bit integer.
+	TypeInt
+	// TypeBigInt is a 64 bit integer.
+	TypeBigInt
+	// TypeTime is a date with time.
+	TypeTime
+)
+
+// Dialect provides the database specific SQL of a SQLStore.
+type Dialect interface {
+	// Name returns the name of the dialect, e.g. "postgres".
+	Name() string
+	// Placeholder returns the bind parameter for the n-th argument, starting at 1.
+	Placeholder(n int) string
+	// QuoteIdent quotes an identifier like a table or schema name.
+	QuoteIdent(name string) string
+	// ColumnType returns the database type for t.
+	ColumnType(t ColumnType) string
+	// CreateTable returns a statement, which creates table with the given
+	// definition of columns and constraints if it does not exist.
+	CreateTable(table string, definition string) string
+}
+
+// AdvisoryLockDialect is implemented by dialects which support advisory locks.
+// If such a dialect is set with WithDialect, the SQLStore uses the advisory lock
+// unless another one is set with WithAdvisoryLock.
+type AdvisoryLockDialect interface {
+	// AdvisoryLock returns an AdvisoryLocker for the lock with the given name.
+	AdvisoryLock(name string) AdvisoryLocker
+}
+
+var (
+	// DialectPostgres is the Dialect for PostgreSQL.
+	DialectPostgres Dialect = postgresDialect{}
+	// DialectMySQL is the Dialect for MySQL and MariaDB.
+	DialectMySQL Dialect = mysqlDialect{}
+	// DialectSQLite is the Dialect for SQLite.
+	DialectSQLite Dialect = sqliteDialect{}
+	// DialectSQLServer is the Dialect for Microsoft SQL Server.
+	DialectSQLServer Dialect = sqlServerDialect{}
+)
+
+// genericDialect is the default Dialect of a SQLStore, which works with MySQL and SQLite.
+type genericDialect struct{}
+
+func (genericDialect) Name() string { return "generic" }
+
+func (genericDialect) Placeholder(int) string { return "?" }
+
+// QuoteIdent uses backticks, which are understood by MySQL and SQLite.
+func (genericDialect) QuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (genericDialect) ColumnType(t ColumnType) string {
+	switch t {
+	case TypeID:
+		return "VARCHAR(100)"
+	case TypeString:
+		return "VARCHAR(255)"
+	case TypeText:
+		return "TEXT"
+	case TypeInt:
+		return "INT"
+	case TypeBigInt:
+		return "BIGINT"
+	case TypeTime:
+		return "DATETIME"
+	}
+	panic(fmt.Sprintf("unknown column type %d", t))
+}
+
+func (genericDialect) CreateTable(table string, definition string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table, definition)
+}
+
+type mysqlDialect struct{ genericDialect }
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+func (mysqlDialect) AdvisoryLock(name string) AdvisoryLocker {
+	return MySQLAdvisoryLock(name)
+}
+
+type sqliteDialect struct{ genericDialect }
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+type postgresDialect struct{ genericDialect }
+
+func (postgresDialect) Name() string { return "postgres" }
+
+func (postgresDialect) Placeholder(n int) string { return fmt.Sprintf("$%d", n) }
+
+func (postgresDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (d postgresDialect) ColumnType(t ColumnType) string {
+	if t == TypeTime {
+		return "TIMESTAMP"
+	}
+	return d.genericDialect.ColumnType(t)
+}
+
+// AdvisoryLock uses a hash of name as key, as postgres locks are identified by numbers.
+func (postgresDialect) AdvisoryLock(name string) AdvisoryLocker {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return PostgresAdvisoryLock(int64(h.Sum64()))
+}
+
+type sqlServerDialect struct{}
+
+func (sqlServerDialect) Name() string { return "sqlserver" }
+
+func (sqlServerDialect) Placeholder(n int) string { return fmt.Sprintf("@p%d", n) }
+
+func (sqlServerDialect) QuoteIdent(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+func (sqlServerDialect) ColumnType(t ColumnType) string {
+	switch t {
+	case TypeID:
+		return "NVARCHAR(100)"
+	case TypeString:
+		return "NVARCHAR(255)"
+	case TypeText:
+		return "NVARCHAR(MAX)"
+	case TypeInt:
+		return "INT"
+	case TypeBigInt:
+		return "BIGINT"
+	case TypeTime:
+		return "DATETIME2"
+	}
+	panic(fmt.Sprintf("unknown column type %d", t))
+}
+
+func (sqlServerDialect) CreateTable(table string, definition string) string {
+	return fmt.Sprintf(
+		"IF OBJECT_ID(N'%s', N'U') IS NULL CREATE TABLE %s (%s)",
+		strings.ReplaceAll(table, "'", "''"), table, definition,
+	)
+}
+
+func (sqlServerDialect) AdvisoryLock(name string) AdvisoryLocker {
+	return SQLServerAdvisoryLock(name)
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -12,36 +13,87 @@
 type entry struct {
 	ID       string    `json:"id"`
 	Executed time.Time `json:"executed"`
+	Checksum string    `json:"checksum,omitempty"`
+
+	// the metadata of a Record is missing for migrations recorded with Up.
+	Started  *time.Time    `json:"started,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Hostname string        `json:"hostname,omitempty"`
+	PID      int           `json:"pid,omitempty"`
+	Version  string        `json:"version,omitempty"`
+}
+
+func (e entry) record() Record {
+	rec := Record{
+		ID:        e.ID,
+		Direction: DirectionUp,
+		Executed:  e.Executed,
+		Duration:  e.Duration,
+		Hostname:  e.Hostname,
+		PID:       e.PID,
+		Version:   e.Version,
+		Checksum:  e.Checksum,
+	}
+	if e.Started != nil {
+		rec.Started = *e.Started
+	}
+	return rec
 }
 
 // FileStore store the migration state in a json based file.
+//
+// Lock is safe across processes on the same host: it takes an OS-level
+// advisory lock on the sibling file "<path>.lock" and re-reads the state file.
+//
+// The state file is written atomically and the previous state is kept in
+// "<path>.bak", which is restored if the state file is corrupt.
+// With WithHistory it also keeps the append-only history of all executions.
 type FileStore struct {
 	path       string
-	Migrations []entry `json:"migrations"`
-	mu         sync.Mutex
+	Migrations []entry        `json:"migrations"`
+	History    []HistoryEntry `json:"history,omitempty"`
+	cfg        config
+
+	// state guards the migrations, the history and recovered, which may be
+	// read during a run. It is held while the state is read or saved.
+	state sync.RWMutex
+
+	// held is acquired by Lock within the process, lockFile holds the OS-level lock.
+	held     semaphore
+	lockFile *os.File
+	// recovered is set if the state was restored from the backup.
+	recovered *CorruptStateError
 }
 
 // NewFileStoreWithPath will return a FileStore with a custom path.
-func NewFileStoreWithPath(path string) *FileStore {
-	return &FileStore{path: path}
+func NewFileStoreWithPath(path string, opts ...Option) *FileStore {
+	return &FileStore{path: path, cfg: newConfig(opts), held: newSemaphore()}
 }
 
 // NewFileStore will return a FileStore with the default path ".mygrate".
-func NewFileStore() *FileStore {
-	return NewFileStoreWithPath(".mygrate")
+func NewFileStore(opts ...Option) *FileStore {
+	return NewFileStoreWithPath(".mygrate", opts...)
 }
 
+// save writes the state atomically and keeps the previous state in "<path>.bak".
+// The caller holds the write lock of state.
 func (f *FileStore) save() error {
 	buf, err := json.MarshalIndent(f, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	if err := os.WriteFile(f.path, buf, 0600); err != nil {
-		return err
+	if prev, err := os.ReadFile(f.path); err == nil && json.Valid(prev) {
+		if err := writeFileAtomic(f.backupPath(), prev, 0600); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return writeFileAtomic(f.path, buf, 0600)
+}
+
+func (f *FileStore) backupPath() string {
+	return f.path + ".bak"
 }
 
 // Init implements mygrate.Store.
@@ -50,20 +102,82 @@ func (f *FileStore) Init() error {
 		return err
 	}
 
+	f.state.Lock()
+	defer f.state.Unlock()
+
+	return f.load()
+}
+
+// load reads the state file, a missing file is an empty state.
+// The caller holds the write lock of state.
+func (f *FileStore) load() error {
+	f.reset()
+
 	buf, err := os.ReadFile(f.path)
 	if err != nil {
 		return nil
 	}
 
 	if err := json.Unmarshal(buf, f); err != nil {
+		return f.restore(err)
+	}
+
+	return nil
+}
+
+// reset clears the state before it is read again.
+func (f *FileStore) reset() {
+	f.Migrations = nil
+	f.History = nil
+}
+
+// restore replaces a corrupt state file with its backup. The corrupt file is
+// kept as "<path>.corrupt" for inspection.
+func (f *FileStore) restore(parseErr error) error {
+	corrupt := &CorruptStateError{Path: f.path, Err: parseErr}
+	f.reset()
+
+	buf, err := os.ReadFile(f.backupPath())
+	if err != nil {
+		return corrupt
+	}
+	if err := json.Unmarshal(buf, f); err != nil {
+		f.reset()
+		return corrupt
+	}
+
+	if err := os.Rename(f.path, f.path+".corrupt"); err != nil {
 		return err
 	}
+	if err := writeFileAtomic(f.path, buf, 0600); err != nil {
+		return err
+	}
+
+	corrupt.Backup = f.backupPath()
+	f.recovered = corrupt
 
 	return nil
 }
 
+// Recovered returns a *CorruptStateError if the state file was corrupt and
+// has been restored from its backup, otherwise nil.
+// The backup is the state before the last change, so the last applied
+// or rolled back migration may be missing from it.
+func (f *FileStore) Recovered() error {
+	f.state.RLock()
+	defer f.state.RUnlock()
+
+	if f.recovered == nil {
+		return nil
+	}
+	return f.recovered
+}
+
 // FindDone implements mygrate.Store.
 func (f *FileStore) FindDone() ([]string, error) {
+	f.state.RLock()
+	defer f.state.RUnlock()
+
 	done := make([]string, 0, len(f.Migrations))
 	for _, v := range f.Migrations {
 		done = append(done, v.ID)
@@ -71,8 +185,23 @@ func (f *FileStore) FindDone() ([]string, error) {
 	return done, nil
 }
 
+// FindExecuted implements mygrate.ExecutedFinder.
+func (f *FileStore) FindExecuted(ctx context.Context) (map[string]time.Time, error) {
+	f.state.RLock()
+	defer f.state.RUnlock()
+
+	executed := make(map[string]time.Time, len(f.Migrations))
+	for _, v := range f.Migrations {
+		executed[v.ID] = v.Executed
+	}
+	return executed, nil
+}
+
 // Up implements mygrate.Store.
 func (f *FileStore) Up(id string, executed time.Time) error {
+	f.state.Lock()
+	defer f.state.Unlock()
+
 	f.Migrations = append(f.Migrations, entry{
 		ID:       id,
 		Executed: executed,
@@ -80,8 +209,47 @@ func (f *FileStore) Up(id string, executed time.Time) error {
 	return f.save()
 }
 
+// UpRecord implements mygrate.RecordStore.
+func (f *FileStore) UpRecord(ctx context.Context, rec Record) error {
+	f.state.Lock()
+	defer f.state.Unlock()
+
+	started := rec.Started
+	f.Migrations = append(f.Migrations, entry{
+		ID:       rec.ID,
+		Executed: rec.Executed,
+		Checksum: rec.Checksum,
+		Started:  &started,
+		Duration: rec.Duration,
+		Hostname: rec.Hostname,
+		PID:      rec.PID,
+		Version:  rec.Version,
+	})
+	return f.save()
+}
+
+// DownRecord implements mygrate.RecordStore.
+func (f *FileStore) DownRecord(ctx context.Context, rec Record) error {
+	return f.Down(rec.ID, rec.Executed)
+}
+
+// FindRecords implements mygrate.RecordStore.
+func (f *FileStore) FindRecords(ctx context.Context) (map[string]Record, error) {
+	f.state.RLock()
+	defer f.state.RUnlock()
+
+	records := make(map[string]Record, len(f.Migrations))
+	for _, v := range f.Migrations {
+		records[v.ID] = v.record()
+	}
+	return records, nil
+}
+
 // Down implements mygrate.Store.
 func (f *FileStore) Down(id string, executed time.Time) error {
+	f.state.Lock()
+	defer f.state.Unlock()
+
 	index := -1
 	for i, e := range f.Migrations {
 		if e.ID == id {
@@ -99,14 +267,143 @@ func (f *FileStore) Down(id string, executed time.Time) error {
 	return f.save()
 }
 
+// SetChecksum implements mygrate.ChecksumStore.
+func (f *FileStore) SetChecksum(ctx context.Context, id string, checksum string) error {
+	f.state.Lock()
+	defer f.state.Unlock()
+
+	for i, e := range f.Migrations {
+		if e.ID == id {
+			f.Migrations[i].Checksum = checksum
+			return f.save()
+		}
+	}
+	return fmt.Errorf("%s %w", id, ErrIDNotFound)
+}
+
+// FindChecksums implements mygrate.ChecksumStore.
+func (f *FileStore) FindChecksums(ctx context.Context) (map[string]string, error) {
+	f.state.RLock()
+	defer f.state.RUnlock()
+
+	checksums := make(map[string]string, len(f.Migrations))
+	for _, v := range f.Migrations {
+		if v.Checksum != "" {
+			checksums[v.ID] = v.Checksum
+		}
+	}
+	return checksums, nil
+}
+
+// AppendHistory implements mygrate.HistoryStore.
+// It returns ErrHistoryUnsupported without WithHistory.
+func (f *FileStore) AppendHistory(ctx context.Context, e HistoryEntry) error {
+	if !f.cfg.history {
+		return ErrHistoryUnsupported
+	}
+
+	f.state.Lock()
+	defer f.state.Unlock()
+
+	f.History = append(f.History, e)
+	return f.save()
+}
+
+// FindHistory implements mygrate.HistoryStore.
+// It returns ErrHistoryUnsupported without WithHistory.
+func (f *FileStore) FindHistory(ctx context.Context) ([]HistoryEntry, error) {
+	if !f.cfg.history {
+		return nil, ErrHistoryUnsupported
+	}
+
+	f.state.RLock()
+	defer f.state.RUnlock()
+
+	return append([]HistoryEntry(nil), f.History...), nil
+}
+
 // Lock implements mygrate.Locker.
 func (f *FileStore) Lock() error {
-	f.mu.Lock()
+	return f.LockContext(context.Background())
+}
+
+// LockContext implements mygrate.ContextLocker.
+// It waits until the lock is acquired, the lock timeout is exceeded or ctx is done.
+// Afterwards the state file is read again, as another process may have changed it.
+func (f *FileStore) LockContext(ctx context.Context) error {
+	lockPath := f.path + ".lock"
+	deadline := time.Now().Add(f.cfg.lockTimeout)
+
+	// another service of this process may hold the lock of this store
+	if err := waitLock(ctx, deadline, "file "+lockPath, f.cfg.lockTimeout, func() (bool, string, error) {
+		return f.held.tryAcquire(), f.cfg.lockOwner, nil
+	}); err != nil {
+		return err
+	}
+
+	if err := f.lock(ctx, lockPath, deadline); err != nil {
+		f.held.release()
+		return err
+	}
+
 	return nil
 }
 
+func (f *FileStore) lock(ctx context.Context, lockPath string, deadline time.Time) error {
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+
+	if err := waitLock(ctx, deadline, "file "+lockPath, f.cfg.lockTimeout, func() (bool, string, error) {
+		acquired, err := tryLockFile(file)
+		if err != nil || acquired {
+			return acquired, "", err
+		}
+		owner, _ := os.ReadFile(lockPath)
+		return false, string(owner), nil
+	}); err != nil {
+		file.Close()
+		return err
+	}
+	f.lockFile = file
+
+	// the owner is only informational, so errors are ignored
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(f.cfg.lockOwner), 0)
+	}
+
+	f.state.Lock()
+	err = f.load()
+	f.state.Unlock()
+	if err != nil {
+		f.unlockFile()
+		return err
+	}
+
+	return nil
+}
+
+func (f *FileStore) unlockFile() error {
+	if f.lockFile == nil {
+		return nil
+	}
+
+	err := unlockFile(f.lockFile)
+	f.lockFile.Close()
+	f.lockFile = nil
+
+	return err
+}
+
 // Unlock implements mygrate.Locker.
 func (f *FileStore) Unlock() error {
-	f.mu.Unlock()
-	return nil
+	return f.UnlockContext(context.Background())
+}
+
+// UnlockContext implements mygrate.ContextLocker.
+func (f *FileStore) UnlockContext(ctx context.Context) error {
+	defer f.held.release()
+
+	return f.unlockFile()
 }
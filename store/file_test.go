@@ -0,0 +1,136 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+// initFileStore returns an initialized FileStore on path with the given migrations applied one by one.
+func initFileStore(t *testing.T, path string, ids ...string) *store.FileStore {
+	t.Helper()
+
+	f := store.NewFileStoreWithPath(path)
+	if err := f.Init(); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	for _, id := range ids {
+		if err := f.Up(id, time.Now()); err != nil {
+			t.Fatalf(`did not expected err '%s'`, err)
+		}
+	}
+	return f
+}
+
+func TestFileStore_Save_KeepsBackup(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	initFileStore(t, path, "1", "2")
+
+	// the backup is the state before the last change
+	backup := initFileStore(t, path+".bak")
+	done, err := backup.FindDone()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if len(done) != 1 || done[0] != "1" {
+		t.Fatalf(`expected the backup to contain '%v', got '%v'`, []string{"1"}, done)
+	}
+
+	// the temp files of the atomic writes are gone
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if len(names) != 2 || names[0] != "state.json" || names[1] != "state.json.bak" {
+		t.Fatalf(`expected only the state and its backup, got '%v'`, names)
+	}
+}
+
+func TestFileStore_Init_CorruptWithBackup(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	initFileStore(t, path, "1", "2")
+	if err := os.WriteFile(path, []byte(`{"migrations": [`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	f := initFileStore(t, path)
+	done, err := f.FindDone()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if len(done) != 1 || done[0] != "1" {
+		t.Fatalf(`expected the state of the backup '%v', got '%v'`, []string{"1"}, done)
+	}
+
+	var corrupt *store.CorruptStateError
+	if !errors.As(f.Recovered(), &corrupt) || corrupt.Backup != path+".bak" {
+		t.Fatalf(`expected a CorruptStateError with the backup, got '%v'`, f.Recovered())
+	}
+	if buf, err := os.ReadFile(path + ".corrupt"); err != nil || string(buf) != `{"migrations": [` {
+		t.Fatalf(`expected the corrupt state to be kept, got '%s' '%v'`, buf, err)
+	}
+
+	// the restored state file is valid again
+	if err := initFileStore(t, path).Recovered(); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+}
+
+func TestFileStore_Init_CorruptWithoutBackup(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte(`{"migrations": [`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := store.NewFileStoreWithPath(path).Init()
+	var corrupt *store.CorruptStateError
+	if !errors.As(err, &corrupt) || corrupt.Path != path || corrupt.Backup != "" {
+		t.Fatalf(`expected a CorruptStateError without backup, got '%v'`, err)
+	}
+}
+
+func TestFileStore_History_OptIn(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	f := initFileStore(t, path, "1")
+	if err := f.AppendHistory(context.Background(), store.HistoryEntry{Event: store.EventUp}); !errors.Is(err, store.ErrHistoryUnsupported) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, store.ErrHistoryUnsupported, err)
+	}
+	if _, err := f.FindHistory(context.Background()); !errors.Is(err, store.ErrHistoryUnsupported) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, store.ErrHistoryUnsupported, err)
+	}
+
+	f = store.NewFileStoreWithPath(path, store.WithHistory())
+	if err := f.Init(); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if err := f.AppendHistory(context.Background(), store.HistoryEntry{Event: store.EventUp}); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if !strings.Contains(string(buf), `"history"`) {
+		t.Fatalf(`expected the history in the state file, got '%s'`, buf)
+	}
+}
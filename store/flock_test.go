@@ -0,0 +1,107 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows
+// +build darwin dragonfly freebsd linux netbsd openbsd windows
+
+package store_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lanz-dev/go-mygrate/store"
+)
+
+func TestFileStore_Lock_Timeout(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	first := store.NewFileStoreWithPath(path, store.WithLockOwner("first"))
+	second := store.NewFileStoreWithPath(path, store.WithLockTimeout(10*time.Millisecond))
+
+	if err := first.Lock(); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	defer first.Unlock()
+
+	err := second.LockContext(context.Background())
+	if !errors.Is(err, store.ErrLocked) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, store.ErrLocked, err)
+	}
+	var lockErr *store.LockError
+	if !errors.As(err, &lockErr) || lockErr.Owner != "first" {
+		t.Fatalf(`expected a LockError with owner '%s', got '%v'`, "first", err)
+	}
+}
+
+func TestFileStore_Lock_ReadsState(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	first := store.NewFileStoreWithPath(path)
+	second := store.NewFileStoreWithPath(path)
+	for _, f := range []*store.FileStore{first, second} {
+		if err := f.Init(); err != nil {
+			t.Fatalf(`did not expected err '%s'`, err)
+		}
+	}
+
+	if err := first.Lock(); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if err := first.Up("1", time.Now()); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if err := first.Unlock(); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	if err := second.Lock(); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	defer second.Unlock()
+
+	done, err := second.FindDone()
+	if err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if len(done) != 1 || done[0] != "1" {
+		t.Fatalf(`expected the state of the other instance, got '%v'`, done)
+	}
+}
+
+func TestFileStore_Lock_SameStore(t *testing.T) {
+	t.Parallel()
+
+	// e.g. two services of one process share the store
+	f := store.NewFileStoreWithPath(
+		filepath.Join(t.TempDir(), "state.json"),
+		store.WithLockOwner("shared"),
+		store.WithLockTimeout(10*time.Millisecond),
+	)
+	if err := f.Lock(); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	var lockErr *store.LockError
+	if err := f.LockContext(context.Background()); !errors.As(err, &lockErr) || lockErr.Owner != "shared" {
+		t.Fatalf(`expected a LockError with owner '%s', got '%v'`, "shared", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := f.LockContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, context.Canceled, err)
+	}
+
+	if err := f.Unlock(); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if err := f.Lock(); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if err := f.Unlock(); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+}
@@ -0,0 +1,53 @@
+//go:build windows
+// +build windows
+
+package store
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+
+	errLockViolation syscall.Errno = 33
+)
+
+var (
+	modKernel32      = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = modKernel32.NewProc("LockFileEx")
+	procUnlockFileEx = modKernel32.NewProc("UnlockFileEx")
+)
+
+// tryLockFile acquires an exclusive LockFileEx on the first byte of f without waiting.
+func tryLockFile(f *os.File) (bool, error) {
+	var ol syscall.Overlapped
+	r, _, err := procLockFileEx.Call(
+		f.Fd(),
+		lockfileExclusiveLock|lockfileFailImmediately,
+		0,
+		1,
+		0,
+		uintptr(unsafe.Pointer(&ol)),
+	)
+	if r != 0 {
+		return true, nil
+	}
+	if errors.Is(err, errLockViolation) || errors.Is(err, syscall.ERROR_IO_PENDING) {
+		return false, nil
+	}
+	return false, err
+}
+
+func unlockFile(f *os.File) error {
+	var ol syscall.Overlapped
+	r, _, err := procUnlockFileEx.Call(f.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&ol)))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package store
+
+// Events of a HistoryEntry.
+const (
+	EventUp     = "up"     // EventUp means the up func was executed.
+	EventDown   = "down"   // EventDown means the down func was executed.
+	EventFailed = "failed" // EventFailed means the up or down func or its bookkeeping failed.
+	EventRepair = "repair" // EventRepair means a changed checksum was accepted.
+)
+
+// HistoryEntry is a single event of the append-only history, see mygrate.HistoryStore.
+// For EventRepair only the ID, the execution time, the host metadata and the
+// accepted checksum of the Record are set.
+type HistoryEntry struct {
+	Record
+
+	Event  string `json:"event"`
+	Reason string `json:"reason,omitempty"` // Reason of the run, e.g. "pending", "redo" or "rollback to 0001".
+	Error  string `json:"error,omitempty"`  // Error is set for EventFailed.
+}
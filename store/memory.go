@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -9,13 +10,22 @@
 // MemoryStore store the migration state in a map.
 type MemoryStore struct {
 	migrations map[string]time.Time
-	mu         sync.Mutex
+	checksums  map[string]string
+	records    map[string]Record
+	history    []HistoryEntry
+
+	// state guards the maps and the history, which may be read during a run,
+	// mu is held by Lock for the whole run.
+	state sync.RWMutex
+	mu    sync.Mutex
 }
 
 // NewMemoryStore will return a MemoryStore.
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
 		migrations: map[string]time.Time{},
+		checksums:  map[string]string{},
+		records:    map[string]Record{},
 	}
 }
 
@@ -26,6 +36,9 @@ func (m *MemoryStore) Init() error {
 
 // FindDone implements mygrate.Store.
 func (m *MemoryStore) FindDone() ([]string, error) {
+	m.state.RLock()
+	defer m.state.RUnlock()
+
 	done := make([]string, 0, len(m.migrations))
 	for ID := range m.migrations {
 		done = append(done, ID)
@@ -33,21 +46,119 @@ func (m *MemoryStore) FindDone() ([]string, error) {
 	return done, nil
 }
 
+// FindExecuted implements mygrate.ExecutedFinder.
+func (m *MemoryStore) FindExecuted(ctx context.Context) (map[string]time.Time, error) {
+	m.state.RLock()
+	defer m.state.RUnlock()
+
+	executed := make(map[string]time.Time, len(m.migrations))
+	for ID, at := range m.migrations {
+		executed[ID] = at
+	}
+	return executed, nil
+}
+
 // Up implements mygrate.Store.
 func (m *MemoryStore) Up(id string, executed time.Time) error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
 	m.migrations[id] = executed
 	return nil
 }
 
 // Down implements mygrate.Store.
 func (m *MemoryStore) Down(id string, executed time.Time) error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
 	if _, ok := m.migrations[id]; !ok {
 		return fmt.Errorf("%s %w", id, ErrIDNotFound)
 	}
 	delete(m.migrations, id)
+	delete(m.checksums, id)
+	delete(m.records, id)
 	return nil
 }
 
+// UpRecord implements mygrate.RecordStore.
+func (m *MemoryStore) UpRecord(ctx context.Context, rec Record) error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	m.records[rec.ID] = rec
+	if rec.Checksum != "" {
+		m.checksums[rec.ID] = rec.Checksum
+	}
+	m.migrations[rec.ID] = rec.Executed
+	return nil
+}
+
+// DownRecord implements mygrate.RecordStore.
+func (m *MemoryStore) DownRecord(ctx context.Context, rec Record) error {
+	return m.Down(rec.ID, rec.Executed)
+}
+
+// FindRecords implements mygrate.RecordStore.
+// Migrations which were recorded with Up only have their ID and execution time.
+func (m *MemoryStore) FindRecords(ctx context.Context) (map[string]Record, error) {
+	m.state.RLock()
+	defer m.state.RUnlock()
+
+	records := make(map[string]Record, len(m.migrations))
+	for ID, at := range m.migrations {
+		rec, ok := m.records[ID]
+		if !ok {
+			rec = Record{ID: ID, Direction: DirectionUp, Executed: at}
+		}
+		// a repair may have changed the checksum since
+		rec.Checksum = m.checksums[ID]
+		records[ID] = rec
+	}
+	return records, nil
+}
+
+// SetChecksum implements mygrate.ChecksumStore.
+func (m *MemoryStore) SetChecksum(ctx context.Context, id string, checksum string) error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	if _, ok := m.migrations[id]; !ok {
+		return fmt.Errorf("%s %w", id, ErrIDNotFound)
+	}
+	m.checksums[id] = checksum
+	return nil
+}
+
+// FindChecksums implements mygrate.ChecksumStore.
+func (m *MemoryStore) FindChecksums(ctx context.Context) (map[string]string, error) {
+	m.state.RLock()
+	defer m.state.RUnlock()
+
+	checksums := make(map[string]string, len(m.checksums))
+	for ID, checksum := range m.checksums {
+		checksums[ID] = checksum
+	}
+	return checksums, nil
+}
+
+// AppendHistory implements mygrate.HistoryStore.
+func (m *MemoryStore) AppendHistory(ctx context.Context, e HistoryEntry) error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	m.history = append(m.history, e)
+	return nil
+}
+
+// FindHistory implements mygrate.HistoryStore.
+func (m *MemoryStore) FindHistory(ctx context.Context) ([]HistoryEntry, error) {
+	m.state.RLock()
+	defer m.state.RUnlock()
+
+	return append([]HistoryEntry(nil), m.history...), nil
+}
+
 // Lock implements mygrate.Locker.
 func (m *MemoryStore) Lock() error {
 	m.mu.Lock()
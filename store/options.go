@@ -0,0 +1,120 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	defaultLockTimeout = time.Minute
+	defaultLockTTL     = time.Minute
+)
+
+// Option configures a store. Options which do not apply to a store are ignored.
+type Option func(c *config)
+
+type config struct {
+	lockTimeout  time.Duration
+	lockTTL      time.Duration
+	lockOwner    string
+	advisoryLock AdvisoryLocker
+	dialect      Dialect
+	table        string
+	schema       string
+	history      bool
+}
+
+func newConfig(opts []Option) config {
+	c := config{
+		lockTimeout: defaultLockTimeout,
+		lockTTL:     defaultLockTTL,
+		lockOwner:   defaultLockOwner(),
+		table:       defaultTable,
+	}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return c
+}
+
+// defaultLockOwner identifies the current process, e.g. "myhost:1234:1612345678901234567".
+func defaultLockOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d:%d", host, os.Getpid(), time.Now().UnixNano())
+}
+
+// WithLockTimeout sets how long Lock waits for a lock held by someone else,
+// before it gives up with a LockError. Default is one minute.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.lockTimeout = timeout
+	}
+}
+
+// WithLockTTL sets after which time a lock is treated as abandoned, e.g.
+// because the owning process crashed. While the lock is held, its expiry is
+// extended every third of the TTL, so a run may take longer than the TTL.
+// Default is one minute. Applies to SQLStore.
+func WithLockTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.lockTTL = ttl
+	}
+}
+
+// WithLockOwner sets the owner which is written to the lock.
+// Default is the hostname and the process id. Applies to SQLStore.
+func WithLockOwner(owner string) Option {
+	return func(c *config) {
+		c.lockOwner = owner
+	}
+}
+
+// WithAdvisoryLock additionally acquires a database specific session lock,
+// see PostgresAdvisoryLock, MySQLAdvisoryLock and SQLServerAdvisoryLock.
+// Applies to SQLStore.
+func WithAdvisoryLock(locker AdvisoryLocker) Option {
+	return func(c *config) {
+		c.advisoryLock = locker
+	}
+}
+
+// WithDialect sets the Dialect, e.g. DialectPostgres. Applies to SQLStore.
+func WithDialect(dialect Dialect) Option {
+	return func(c *config) {
+		c.dialect = dialect
+	}
+}
+
+// WithTableName sets the name of the table, default is "mygrate".
+// The lock table is named "<name>_lock" and the history table "<name>_history".
+// Use different names to run independent sets of migrations against the same
+// database. Applies to SQLStore.
+func WithTableName(name string) Option {
+	return func(c *config) {
+		c.table = name
+	}
+}
+
+// WithHistory keeps an append-only history of every up, down, failure and
+// repair, see mygrate.HistoryStore. Without it, the history is not written
+// and reading it returns ErrHistoryUnsupported. Applies to SQLStore, which
+// creates the table "<name>_history", and FileStore, which keeps the history
+// in the state file. The MemoryStore always keeps it.
+func WithHistory() Option {
+	return func(c *config) {
+		c.history = true
+	}
+}
+
+// WithSchema qualifies the tables with the given schema. Applies to SQLStore.
+func WithSchema(schema string) Option {
+	return func(c *config) {
+		c.schema = schema
+	}
+}
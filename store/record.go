@@ -0,0 +1,28 @@
+package store
+
+import (
+	"time"
+)
+
+// Directions of a Record.
+const (
+	DirectionUp   = "up"
+	DirectionDown = "down"
+)
+
+// Record describes a single execution of a migration, see mygrate.RecordStore.
+type Record struct {
+	ID        string `json:"id"`
+	Direction string `json:"direction"` // DirectionUp or DirectionDown.
+
+	Executed time.Time     `json:"executed"` // Executed is the time the store recorded the execution.
+	Started  time.Time     `json:"started"`  // Started is the time the up or down func started.
+	Duration time.Duration `json:"duration"` // Duration of the up or down func.
+
+	Hostname string `json:"hostname"`
+	PID      int    `json:"pid"`
+	Version  string `json:"version"` // Version of the application, see mygrate.WithVersion.
+
+	// Checksum of an up execution, which is recorded together with it, see mygrate.WithChecksum.
+	Checksum string `json:"checksum,omitempty"`
+}
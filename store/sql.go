@@ -1,42 +1,121 @@
 package store
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"sync"
 	"time"
 )
 
-const (
-	qryFindDone = `SELECT id FROM mygrate`
-	qryUp       = `INSERT INTO mygrate (id, executed) VALUES (?, ?)`
-	qryDown     = `DELETE FROM mygrate WHERE id = ?`
-	qryCreate   = `CREATE TABLE IF NOT EXISTS mygrate (
-		id VARCHAR(100) NOT NULL,
-		executed DATETIME NOT NULL,
-		PRIMARY KEY (id)
-	)`
-)
+// execer is implemented by *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
 
+// SQLStore store the migration state in a database table.
+// It implements mygrate.TxStore, so migrations registered with RegisterTx
+// and their bookkeeping are committed or rolled back atomically.
+//
+// The table is named mygrate, see WithTableName and WithSchema.
+//
+// Lock is safe across processes: it inserts a row into the table <table>_lock
+// and, if the dialect supports it or WithAdvisoryLock is set, acquires a database advisory lock.
+// The lock row expires after the lock TTL, while it is held its expiry is extended
+// every third of the TTL, see WithLockTTL.
+//
+// With WithHistory every execution is also appended to the history table <table>_history.
 type SQLStore struct {
-	db *sql.DB
-	mu sync.Mutex
+	db  *sql.DB
+	cfg config
+	qry sqlQueries
+
+	// held is acquired by Lock within the process, lockConn holds the session of the advisory lock.
+	held     semaphore
+	lockConn *sql.Conn
+	// stopRefresh stops refreshLock, which closes refreshDone once it returned.
+	stopRefresh chan struct{}
+	refreshDone chan struct{}
+	// lockLost is set by refreshLock if the lock row was gone.
+	lockLost bool
 }
 
-// NewSQLStore returns a neq MySQLStore.
-func NewSQLStore(db *sql.DB) *SQLStore {
-	return &SQLStore{db: db}
+// NewSQLStore returns a new SQLStore.
+// Without WithDialect the SQL works with MySQL and SQLite.
+func NewSQLStore(db *sql.DB, opts ...Option) *SQLStore {
+	cfg := newConfig(opts)
+
+	dialect := cfg.dialect
+	if dialect == nil {
+		dialect = genericDialect{}
+	}
+	if ad, ok := dialect.(AdvisoryLockDialect); ok && cfg.advisoryLock == nil {
+		name := cfg.table
+		if cfg.schema != "" {
+			name = cfg.schema + "." + name
+		}
+		cfg.advisoryLock = ad.AdvisoryLock(name)
+	}
+
+	return &SQLStore{
+		db:   db,
+		cfg:  cfg,
+		qry:  newSQLQueries(dialect, cfg.schema, cfg.table),
+		held: newSemaphore(),
+	}
 }
 
 // Init implements mygrate.Store.
 func (s *SQLStore) Init() error {
-	_, err := s.db.Exec(qryCreate)
+	return s.InitContext(context.Background())
+}
+
+// InitContext implements mygrate.ContextStore.
+func (s *SQLStore) InitContext(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, s.qry.create); err != nil {
+		return err
+	}
+
+	for _, c := range s.qry.columns {
+		if err := s.addColumn(ctx, c); err != nil {
+			return err
+		}
+	}
+
+	if s.cfg.history {
+		if _, err := s.db.ExecContext(ctx, s.qry.historyCreate); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.db.ExecContext(ctx, s.qry.lockCreate)
 	return err
 }
 
+// addColumn adds c to a table which was created by an older version.
+func (s *SQLStore) addColumn(ctx context.Context, c sqlColumn) error {
+	rows, err := s.db.QueryContext(ctx, c.probe)
+	if err == nil {
+		return rows.Close()
+	}
+
+	_, err = s.db.ExecContext(ctx, c.add)
+	return err
+}
+
+// BeginTx implements mygrate.TxStore.
+func (s *SQLStore) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	return s.db.BeginTx(ctx, nil)
+}
+
 // FindDone implements mygrate.Store.
 func (s *SQLStore) FindDone() ([]string, error) {
-	rows, err := s.db.Query(qryFindDone)
+	return s.FindDoneContext(context.Background())
+}
+
+// FindDoneContext implements mygrate.ContextStore.
+func (s *SQLStore) FindDoneContext(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, s.qry.findDone)
 	if err != nil {
 		return nil, err
 	}
@@ -57,9 +136,107 @@ func (s *SQLStore) FindDone() ([]string, error) {
 	return done, nil
 }
 
+// FindExecuted implements mygrate.ExecutedFinder.
+// Note: the driver must be able to scan the executed column into a time.Time,
+// e.g. go-sql-driver/mysql requires parseTime=true.
+func (s *SQLStore) FindExecuted(ctx context.Context) (map[string]time.Time, error) {
+	rows, err := s.db.QueryContext(ctx, s.qry.findExec)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	executed := map[string]time.Time{}
+	for rows.Next() {
+		var id string
+		var at time.Time
+		if err := rows.Scan(&id, &at); err != nil {
+			return nil, err
+		}
+		executed[id] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return executed, nil
+}
+
+// FindRecords implements mygrate.RecordStore.
+// Like FindExecuted, the driver must be able to scan time columns into a time.Time.
+func (s *SQLStore) FindRecords(ctx context.Context) (map[string]Record, error) {
+	rows, err := s.db.QueryContext(ctx, s.qry.findRecs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := map[string]Record{}
+	for rows.Next() {
+		rec := Record{Direction: DirectionUp}
+		var (
+			checksum sql.NullString
+			started  sql.NullTime
+			duration sql.NullInt64
+			hostname sql.NullString
+			pid      sql.NullInt64
+			version  sql.NullString
+		)
+		err := rows.Scan(&rec.ID, &rec.Executed, &checksum, &started, &duration, &hostname, &pid, &version)
+		if err != nil {
+			return nil, err
+		}
+		rec.Checksum = checksum.String
+		rec.Started = started.Time
+		rec.Duration = time.Duration(duration.Int64) * time.Millisecond
+		rec.Hostname = hostname.String
+		rec.PID = int(pid.Int64)
+		rec.Version = version.String
+		records[rec.ID] = rec
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
 // Up implements mygrate.Store.
 func (s *SQLStore) Up(id string, executed time.Time) error {
-	res, err := s.db.Exec(qryUp, id, executed)
+	return s.UpContext(context.Background(), id, executed)
+}
+
+// UpContext implements mygrate.ContextStore.
+func (s *SQLStore) UpContext(ctx context.Context, id string, executed time.Time) error {
+	return s.up(ctx, s.db, Record{ID: id, Executed: executed})
+}
+
+// UpTx implements mygrate.TxStore.
+func (s *SQLStore) UpTx(ctx context.Context, tx *sql.Tx, id string, executed time.Time) error {
+	return s.up(ctx, tx, Record{ID: id, Executed: executed})
+}
+
+// UpRecord implements mygrate.RecordStore.
+func (s *SQLStore) UpRecord(ctx context.Context, rec Record) error {
+	return s.up(ctx, s.db, rec)
+}
+
+// UpRecordTx implements mygrate.TxRecordStore.
+func (s *SQLStore) UpRecordTx(ctx context.Context, tx *sql.Tx, rec Record) error {
+	return s.up(ctx, tx, rec)
+}
+
+// up inserts rec with its checksum, the metadata is NULL if rec only has an ID and the execution time.
+func (s *SQLStore) up(ctx context.Context, db execer, rec Record) error {
+	var meta [5]interface{}
+	if !rec.Started.IsZero() {
+		meta = [5]interface{}{rec.Started, rec.Duration.Milliseconds(), rec.Hostname, rec.PID, rec.Version}
+	}
+
+	res, err := db.ExecContext(
+		ctx, s.qry.up,
+		rec.ID, rec.Executed, nullString(rec.Checksum), meta[0], meta[1], meta[2], meta[3], meta[4],
+	)
 	if err != nil {
 		return err
 	}
@@ -77,7 +254,31 @@ func (s *SQLStore) Up(id string, executed time.Time) error {
 
 // Down implements mygrate.Store.
 func (s *SQLStore) Down(id string, executed time.Time) error {
-	res, err := s.db.Exec(qryDown, id)
+	return s.DownContext(context.Background(), id, executed)
+}
+
+// DownContext implements mygrate.ContextStore.
+func (s *SQLStore) DownContext(ctx context.Context, id string, executed time.Time) error {
+	return s.down(ctx, s.db, id)
+}
+
+// DownTx implements mygrate.TxStore.
+func (s *SQLStore) DownTx(ctx context.Context, tx *sql.Tx, id string, executed time.Time) error {
+	return s.down(ctx, tx, id)
+}
+
+// DownRecord implements mygrate.RecordStore.
+func (s *SQLStore) DownRecord(ctx context.Context, rec Record) error {
+	return s.down(ctx, s.db, rec.ID)
+}
+
+// DownRecordTx implements mygrate.TxRecordStore.
+func (s *SQLStore) DownRecordTx(ctx context.Context, tx *sql.Tx, rec Record) error {
+	return s.down(ctx, tx, rec.ID)
+}
+
+func (s *SQLStore) down(ctx context.Context, db execer, id string) error {
+	res, err := db.ExecContext(ctx, s.qry.down, id)
 	if err != nil {
 		return err
 	}
@@ -93,14 +294,254 @@ func (s *SQLStore) Down(id string, executed time.Time) error {
 	return nil
 }
 
+// SetChecksum implements mygrate.ChecksumStore.
+func (s *SQLStore) SetChecksum(ctx context.Context, id string, checksum string) error {
+	// the affected rows are not checked, MySQL does not count rows whose value did not change
+	_, err := s.db.ExecContext(ctx, s.qry.setSum, checksum, id)
+	return err
+}
+
+// FindChecksums implements mygrate.ChecksumStore.
+func (s *SQLStore) FindChecksums(ctx context.Context) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx, s.qry.findSums)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checksums := map[string]string{}
+	for rows.Next() {
+		var id, checksum string
+		if err := rows.Scan(&id, &checksum); err != nil {
+			return nil, err
+		}
+		checksums[id] = checksum
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return checksums, nil
+}
+
+// AppendHistory implements mygrate.HistoryStore.
+// It returns ErrHistoryUnsupported without WithHistory.
+func (s *SQLStore) AppendHistory(ctx context.Context, e HistoryEntry) error {
+	if !s.cfg.history {
+		return ErrHistoryUnsupported
+	}
+
+	var started, duration interface{}
+	if !e.Started.IsZero() {
+		started, duration = e.Started, e.Duration.Milliseconds()
+	}
+
+	_, err := s.db.ExecContext(ctx, s.qry.historyInsert,
+		e.ID, e.Event, nullString(e.Direction), nullString(e.Reason),
+		nullString(e.Checksum), nullString(e.Error),
+		e.Executed, started, duration, nullString(e.Hostname), e.PID, nullString(e.Version),
+	)
+	return err
+}
+
+// FindHistory implements mygrate.HistoryStore.
+// It returns ErrHistoryUnsupported without WithHistory.
+func (s *SQLStore) FindHistory(ctx context.Context) ([]HistoryEntry, error) {
+	if !s.cfg.history {
+		return nil, ErrHistoryUnsupported
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.qry.historyFind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []HistoryEntry
+	for rows.Next() {
+		var (
+			e                                            HistoryEntry
+			direction, reason, checksum, errMsg, host, v sql.NullString
+			started                                      sql.NullTime
+			duration, pid                                sql.NullInt64
+		)
+		err := rows.Scan(
+			&e.ID, &e.Event, &direction, &reason, &checksum, &errMsg,
+			&e.Executed, &started, &duration, &host, &pid, &v,
+		)
+		if err != nil {
+			return nil, err
+		}
+		e.Direction, e.Reason, e.Checksum, e.Error = direction.String, reason.String, checksum.String, errMsg.String
+		e.Started = started.Time
+		e.Duration = time.Duration(duration.Int64) * time.Millisecond
+		e.Hostname, e.PID, e.Version = host.String, int(pid.Int64), v.String
+		history = append(history, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// nullString returns NULL for an empty string.
+func nullString(v string) sql.NullString {
+	return sql.NullString{String: v, Valid: v != ""}
+}
+
 // Lock implements mygrate.Locker.
 func (s *SQLStore) Lock() error {
-	s.mu.Lock()
+	return s.LockContext(context.Background())
+}
+
+// LockContext implements mygrate.ContextLocker.
+// It waits until the lock is acquired, the lock timeout is exceeded or ctx is done.
+func (s *SQLStore) LockContext(ctx context.Context) error {
+	deadline := time.Now().Add(s.cfg.lockTimeout)
+
+	// another service of this process may hold the lock of this store
+	if err := s.waitLock(ctx, deadline, func() (bool, string, error) {
+		return s.held.tryAcquire(), s.cfg.lockOwner, nil
+	}); err != nil {
+		return err
+	}
+
+	if err := s.lock(ctx, deadline); err != nil {
+		s.held.release()
+		return err
+	}
+
 	return nil
 }
 
+func (s *SQLStore) lock(ctx context.Context, deadline time.Time) error {
+	if s.cfg.advisoryLock != nil {
+		conn, err := s.db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		if err := s.waitLock(ctx, deadline, func() (bool, string, error) {
+			acquired, err := s.cfg.advisoryLock.TryLock(ctx, conn)
+			return acquired, "", err
+		}); err != nil {
+			conn.Close()
+			return err
+		}
+		s.lockConn = conn
+	}
+
+	if err := s.waitLock(ctx, deadline, func() (bool, string, error) {
+		return s.tryLockRow(ctx)
+	}); err != nil {
+		s.advisoryUnlock()
+		return err
+	}
+
+	if s.cfg.lockTTL > 0 {
+		s.stopRefresh, s.refreshDone = make(chan struct{}), make(chan struct{})
+		go s.refreshLock(s.stopRefresh, s.refreshDone)
+	}
+
+	return nil
+}
+
+// refreshLock extends the expiry of the lock row every third of the lock TTL
+// until stop is closed, so a long run does not lose its lock to another process.
+// A failed refresh is retried with the next one. If the row is gone, the lock
+// was lost and refreshLock returns, see UnlockContext.
+func (s *SQLStore) refreshLock(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(s.cfg.lockTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			expires := time.Now().UTC().Add(s.cfg.lockTTL)
+			res, err := s.db.ExecContext(context.Background(), s.qry.lockRefresh, expires, s.cfg.lockOwner)
+			if err != nil {
+				continue
+			}
+			if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+				s.lockLost = true
+				return
+			}
+		}
+	}
+}
+
+// stopRefreshLock stops refreshLock and waits until it returned.
+func (s *SQLStore) stopRefreshLock() {
+	if s.stopRefresh == nil {
+		return
+	}
+
+	close(s.stopRefresh)
+	<-s.refreshDone
+	s.stopRefresh, s.refreshDone = nil, nil
+}
+
+func (s *SQLStore) waitLock(ctx context.Context, deadline time.Time, try func() (bool, string, error)) error {
+	return waitLock(ctx, deadline, "table "+s.qry.lockTable, s.cfg.lockTimeout, try)
+}
+
+// tryLockRow tries to insert the lock row and returns the current owner if it exists already.
+func (s *SQLStore) tryLockRow(ctx context.Context) (bool, string, error) {
+	now := time.Now().UTC()
+	if _, err := s.db.ExecContext(ctx, s.qry.lockExpire, now); err != nil {
+		return false, "", err
+	}
+
+	_, insertErr := s.db.ExecContext(ctx, s.qry.lockInsert, s.cfg.lockOwner, now, now.Add(s.cfg.lockTTL))
+	if insertErr == nil {
+		return true, "", nil
+	}
+
+	// the insert fails on a duplicate key, but also on any other error
+	var owner string
+	err := s.db.QueryRowContext(ctx, s.qry.lockOwner).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, "", insertErr
+	}
+	if err != nil {
+		return false, "", err
+	}
+
+	return false, owner, nil
+}
+
+func (s *SQLStore) advisoryUnlock() {
+	if s.lockConn == nil {
+		return
+	}
+
+	_ = s.cfg.advisoryLock.Unlock(context.Background(), s.lockConn)
+	s.lockConn.Close()
+	s.lockConn = nil
+}
+
 // Unlock implements mygrate.Locker.
 func (s *SQLStore) Unlock() error {
-	s.mu.Unlock()
-	return nil
+	return s.UnlockContext(context.Background())
+}
+
+// UnlockContext implements mygrate.ContextLocker.
+// It returns ErrLockLost if the lock row expired while it was held.
+func (s *SQLStore) UnlockContext(ctx context.Context) error {
+	defer s.held.release()
+	defer s.advisoryUnlock()
+	s.stopRefreshLock()
+
+	lost := s.lockLost
+	s.lockLost = false
+
+	_, err := s.db.ExecContext(ctx, s.qry.lockDelete, s.cfg.lockOwner)
+	if lost {
+		return ErrLockLost
+	}
+	return err
 }
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"fmt"
+)
+
+const defaultTable = "mygrate"
+
+// sqlQueries are the statements of a SQLStore rendered for its Dialect.
+type sqlQueries struct {
+	lockTable    string
+	historyTable string
+
+	create   string
+	columns  []sqlColumn
+	findDone string
+	findExec string
+	findRecs string
+	up       string
+	down     string
+
+	findSums string
+	setSum   string
+
+	historyCreate string
+	historyInsert string
+	historyFind   string
+
+	lockCreate  string
+	lockExpire  string
+	lockInsert  string
+	lockOwner   string
+	lockRefresh string
+	lockDelete  string
+}
+
+// sqlColumn is a column which was added after the first release of the table.
+// probe fails if the column is missing, add creates it in an existing table.
+type sqlColumn struct {
+	probe string
+	add   string
+}
+
+// qualify returns the quoted, optionally schema qualified table name.
+func qualify(d Dialect, schema, table string) string {
+	if schema == "" {
+		return d.QuoteIdent(table)
+	}
+	return d.QuoteIdent(schema) + "." + d.QuoteIdent(table)
+}
+
+func newSQLQueries(d Dialect, schema, name string) sqlQueries {
+	table := qualify(d, schema, name)
+	lockTable := qualify(d, schema, name+"_lock")
+	historyTable := qualify(d, schema, name+"_history")
+	p := d.Placeholder
+	t := d.ColumnType
+
+	return sqlQueries{
+		lockTable:    lockTable,
+		historyTable: historyTable,
+
+		create: d.CreateTable(table, fmt.Sprintf(
+			"id %s NOT NULL, executed %s NOT NULL, checksum %s, "+
+				"started %s, duration_ms %s, hostname %s, pid %s, version %s, PRIMARY KEY (id)",
+			t(TypeID), t(TypeTime), t(TypeString),
+			t(TypeTime), t(TypeBigInt), t(TypeString), t(TypeInt), t(TypeString),
+		)),
+		columns: []sqlColumn{
+			newSQLColumn(table, "checksum", t(TypeString)),
+			newSQLColumn(table, "started", t(TypeTime)),
+			newSQLColumn(table, "duration_ms", t(TypeBigInt)),
+			newSQLColumn(table, "hostname", t(TypeString)),
+			newSQLColumn(table, "pid", t(TypeInt)),
+			newSQLColumn(table, "version", t(TypeString)),
+		},
+		findDone: fmt.Sprintf("SELECT id FROM %s", table),
+		findExec: fmt.Sprintf("SELECT id, executed FROM %s", table),
+		findRecs: fmt.Sprintf(
+			"SELECT id, executed, checksum, started, duration_ms, hostname, pid, version FROM %s", table,
+		),
+		up: fmt.Sprintf(
+			"INSERT INTO %s (id, executed, checksum, started, duration_ms, hostname, pid, version) "+
+				"VALUES (%s, %s, %s, %s, %s, %s, %s, %s)",
+			table, p(1), p(2), p(3), p(4), p(5), p(6), p(7), p(8),
+		),
+		down: fmt.Sprintf("DELETE FROM %s WHERE id = %s", table, p(1)),
+
+		findSums: fmt.Sprintf("SELECT id, checksum FROM %s WHERE checksum IS NOT NULL", table),
+		setSum:   fmt.Sprintf("UPDATE %s SET checksum = %s WHERE id = %s", table, p(1), p(2)),
+
+		// seq orders the history, as neither the clocks of the hosts nor the precision of
+		// the time columns can be trusted. The database assigns it, which is safe as the
+		// history is only written while the lock is held, the primary key refuses duplicates.
+		historyCreate: d.CreateTable(historyTable, fmt.Sprintf(
+			"seq %s NOT NULL, id %s NOT NULL, event %s NOT NULL, direction %s, reason %s, checksum %s, error %s, "+
+				"executed %s NOT NULL, started %s, duration_ms %s, hostname %s, pid %s, version %s, PRIMARY KEY (seq)",
+			t(TypeBigInt), t(TypeID), t(TypeString), t(TypeString), t(TypeString), t(TypeString), t(TypeText),
+			t(TypeTime), t(TypeTime), t(TypeBigInt), t(TypeString), t(TypeInt), t(TypeString),
+		)),
+		historyInsert: fmt.Sprintf(
+			"INSERT INTO %s (seq, id, event, direction, reason, checksum, error, "+
+				"executed, started, duration_ms, hostname, pid, version) "+
+				"SELECT COALESCE(MAX(seq), 0) + 1, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s FROM %s",
+			historyTable, p(1), p(2), p(3), p(4), p(5), p(6), p(7), p(8), p(9), p(10), p(11), p(12), historyTable,
+		),
+		historyFind: fmt.Sprintf(
+			"SELECT id, event, direction, reason, checksum, error, "+
+				"executed, started, duration_ms, hostname, pid, version FROM %s ORDER BY seq",
+			historyTable,
+		),
+
+		lockCreate: d.CreateTable(lockTable, fmt.Sprintf(
+			"id %s NOT NULL, owner %s NOT NULL, acquired %s NOT NULL, expires %s NOT NULL, PRIMARY KEY (id)",
+			t(TypeInt), t(TypeString), t(TypeTime), t(TypeTime),
+		)),
+		lockExpire: fmt.Sprintf("DELETE FROM %s WHERE id = 1 AND expires < %s", lockTable, p(1)),
+		lockInsert: fmt.Sprintf(
+			"INSERT INTO %s (id, owner, acquired, expires) VALUES (1, %s, %s, %s)",
+			lockTable, p(1), p(2), p(3),
+		),
+		lockOwner:   fmt.Sprintf("SELECT owner FROM %s WHERE id = 1", lockTable),
+		lockRefresh: fmt.Sprintf("UPDATE %s SET expires = %s WHERE id = 1 AND owner = %s", lockTable, p(1), p(2)),
+		lockDelete:  fmt.Sprintf("DELETE FROM %s WHERE id = 1 AND owner = %s", lockTable, p(1)),
+	}
+}
+
+func newSQLColumn(table, name, definition string) sqlColumn {
+	return sqlColumn{
+		probe: fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0", name, table),
+		add:   fmt.Sprintf("ALTER TABLE %s ADD %s %s", table, name, definition),
+	}
+}
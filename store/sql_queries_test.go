@@ -0,0 +1,180 @@
+package store
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewSQLQueries(t *testing.T) {
+	t.Parallel()
+
+	const (
+		genericCreate = "CREATE TABLE IF NOT EXISTS `mygrate` (id VARCHAR(100) NOT NULL, executed DATETIME NOT NULL, " +
+			"checksum VARCHAR(255), started DATETIME, duration_ms BIGINT, hostname VARCHAR(255), pid INT, " +
+			"version VARCHAR(255), PRIMARY KEY (id))"
+		genericUp = "INSERT INTO `mygrate` (id, executed, checksum, started, duration_ms, hostname, pid, version) " +
+			"VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+		genericDown = "DELETE FROM `mygrate` WHERE id = ?"
+	)
+
+	tests := map[string]struct {
+		dialect Dialect
+		create  string
+		up      string
+		down    string
+	}{
+		"generic": {
+			dialect: genericDialect{},
+			create:  genericCreate,
+			up:      genericUp,
+			down:    genericDown,
+		},
+		"mysql": {
+			dialect: DialectMySQL,
+			create:  genericCreate,
+			up:      genericUp,
+			down:    genericDown,
+		},
+		"sqlite": {
+			dialect: DialectSQLite,
+			create:  genericCreate,
+			up:      genericUp,
+			down:    genericDown,
+		},
+		"postgres": {
+			dialect: DialectPostgres,
+			create: `CREATE TABLE IF NOT EXISTS "mygrate" (id VARCHAR(100) NOT NULL, executed TIMESTAMP NOT NULL, ` +
+				`checksum VARCHAR(255), started TIMESTAMP, duration_ms BIGINT, hostname VARCHAR(255), pid INT, ` +
+				`version VARCHAR(255), PRIMARY KEY (id))`,
+			up: `INSERT INTO "mygrate" (id, executed, checksum, started, duration_ms, hostname, pid, version) ` +
+				`VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			down: `DELETE FROM "mygrate" WHERE id = $1`,
+		},
+		"sqlserver": {
+			dialect: DialectSQLServer,
+			create: `IF OBJECT_ID(N'[mygrate]', N'U') IS NULL CREATE TABLE [mygrate] (id NVARCHAR(100) NOT NULL, ` +
+				`executed DATETIME2 NOT NULL, checksum NVARCHAR(255), started DATETIME2, duration_ms BIGINT, ` +
+				`hostname NVARCHAR(255), pid INT, version NVARCHAR(255), PRIMARY KEY (id))`,
+			up: `INSERT INTO [mygrate] (id, executed, checksum, started, duration_ms, hostname, pid, version) ` +
+				`VALUES (@p1, @p2, @p3, @p4, @p5, @p6, @p7, @p8)`,
+			down: `DELETE FROM [mygrate] WHERE id = @p1`,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			q := newSQLQueries(tt.dialect, "", defaultTable)
+			if q.create != tt.create {
+				t.Fatalf("expected create\n%s\ngot\n%s", tt.create, q.create)
+			}
+			if q.up != tt.up {
+				t.Fatalf("expected up\n%s\ngot\n%s", tt.up, q.up)
+			}
+			if q.down != tt.down {
+				t.Fatalf("expected down\n%s\ngot\n%s", tt.down, q.down)
+			}
+		})
+	}
+}
+
+func TestNewSQLStore_Qualify(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		dialect Dialect
+		table   string
+		lock    string
+		history string
+		create  string
+	}{
+		"generic": {
+			table:   "`app`.`my\"ta]b``le`",
+			lock:    "`app`.`my\"ta]b``le_lock`",
+			history: "`app`.`my\"ta]b``le_history`",
+			create:  "CREATE TABLE IF NOT EXISTS `app`.`my\"ta]b``le` (",
+		},
+		"mysql": {
+			dialect: DialectMySQL,
+			table:   "`app`.`my\"ta]b``le`",
+			lock:    "`app`.`my\"ta]b``le_lock`",
+			history: "`app`.`my\"ta]b``le_history`",
+			create:  "CREATE TABLE IF NOT EXISTS `app`.`my\"ta]b``le` (",
+		},
+		"sqlite": {
+			dialect: DialectSQLite,
+			table:   "`app`.`my\"ta]b``le`",
+			lock:    "`app`.`my\"ta]b``le_lock`",
+			history: "`app`.`my\"ta]b``le_history`",
+			create:  "CREATE TABLE IF NOT EXISTS `app`.`my\"ta]b``le` (",
+		},
+		"postgres": {
+			dialect: DialectPostgres,
+			table:   `"app"."my""ta]b` + "`" + `le"`,
+			lock:    `"app"."my""ta]b` + "`" + `le_lock"`,
+			history: `"app"."my""ta]b` + "`" + `le_history"`,
+			create:  `CREATE TABLE IF NOT EXISTS "app"."my""ta]b` + "`" + `le" (`,
+		},
+		"sqlserver": {
+			dialect: DialectSQLServer,
+			table:   "[app].[my\"ta]]b`le]",
+			lock:    "[app].[my\"ta]]b`le_lock]",
+			history: "[app].[my\"ta]]b`le_history]",
+			create:  "IF OBJECT_ID(N'[app].[my\"ta]]b`le]', N'U') IS NULL CREATE TABLE [app].[my\"ta]]b`le] (",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			s := NewSQLStore(nil, WithDialect(tt.dialect), WithSchema("app"), WithTableName("my\"ta]b`le"))
+			if s.qry.lockTable != tt.lock {
+				t.Fatalf(`expected lock table '%s', got '%s'`, tt.lock, s.qry.lockTable)
+			}
+			if s.qry.historyTable != tt.history {
+				t.Fatalf(`expected history table '%s', got '%s'`, tt.history, s.qry.historyTable)
+			}
+			if !strings.HasPrefix(s.qry.create, tt.create) {
+				t.Fatalf(`expected create to start with '%s', got '%s'`, tt.create, s.qry.create)
+			}
+			if expected := "DELETE FROM " + tt.table + " WHERE id = "; !strings.HasPrefix(s.qry.down, expected) {
+				t.Fatalf(`expected down to start with '%s', got '%s'`, expected, s.qry.down)
+			}
+		})
+	}
+}
+
+func TestQualify(t *testing.T) {
+	t.Parallel()
+
+	if got := qualify(DialectPostgres, "", `a"b`); got != `"a""b"` {
+		t.Fatalf(`expected '%s', got '%s'`, `"a""b"`, got)
+	}
+	if got := qualify(DialectSQLServer, `s]`, "t"); got != "[s]]].[t]" {
+		t.Fatalf(`expected '%s', got '%s'`, "[s]]].[t]", got)
+	}
+}
+
+func TestNewSQLQueries_History(t *testing.T) {
+	t.Parallel()
+
+	q := newSQLQueries(DialectPostgres, "", defaultTable)
+
+	// the database assigns seq, not the clock of the host
+	expected := `INSERT INTO "mygrate_history" (seq, id, event, direction, reason, checksum, error, ` +
+		`executed, started, duration_ms, hostname, pid, version) ` +
+		`SELECT COALESCE(MAX(seq), 0) + 1, $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12 FROM "mygrate_history"`
+	if q.historyInsert != expected {
+		t.Fatalf("expected history insert\n%s\ngot\n%s", expected, q.historyInsert)
+	}
+	if !strings.HasSuffix(q.historyCreate, "PRIMARY KEY (seq))") {
+		t.Fatalf(`expected seq to be the primary key, got '%s'`, q.historyCreate)
+	}
+	if !strings.HasSuffix(q.historyFind, "ORDER BY seq") {
+		t.Fatalf(`expected the history to be ordered by seq, got '%s'`, q.historyFind)
+	}
+}
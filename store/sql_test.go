@@ -0,0 +1,474 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingDriver is a database/sql driver which records the executed statements and their arguments.
+type recordingDriver struct {
+	mu    sync.Mutex
+	execs []string
+	args  [][]driver.Value
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) { return recordingConn{d: d}, nil }
+
+func (d *recordingDriver) count(prefix string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := 0
+	for _, q := range d.execs {
+		if strings.HasPrefix(q, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return recordingStmt{d: c.d, query: query}, nil
+}
+func (c recordingConn) Close() error              { return nil }
+func (c recordingConn) Begin() (driver.Tx, error) { return nil, errors.New("not implemented") }
+
+type recordingStmt struct {
+	d     *recordingDriver
+	query string
+}
+
+func (s recordingStmt) Close() error  { return nil }
+func (s recordingStmt) NumInput() int { return -1 }
+
+func (s recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.execs = append(s.d.execs, s.query)
+	s.d.args = append(s.d.args, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s recordingStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+var (
+	recordingDriverSeq   int
+	recordingDriverSeqMu sync.Mutex
+)
+
+// openRecordingDB returns a *sql.DB backed by a new recordingDriver.
+func openRecordingDB(t *testing.T) (*sql.DB, *recordingDriver) {
+	t.Helper()
+
+	recordingDriverSeqMu.Lock()
+	recordingDriverSeq++
+	name := fmt.Sprintf("store-recording-%d", recordingDriverSeq)
+	recordingDriverSeqMu.Unlock()
+
+	d := &recordingDriver{}
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+func TestSQLStore_LockRefresh(t *testing.T) {
+	t.Parallel()
+
+	db, d := openRecordingDB(t)
+	s := NewSQLStore(db, WithLockTTL(30*time.Millisecond))
+
+	if err := s.LockContext(context.Background()); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	// the run takes longer than the TTL
+	for i := 0; d.count("UPDATE `mygrate_lock` SET expires") < 2; i++ {
+		if i == 1000 {
+			t.Fatal(`expected the lock to be refreshed`)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := s.UnlockContext(context.Background()); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	refreshed := d.count("UPDATE `mygrate_lock` SET expires")
+	time.Sleep(50 * time.Millisecond)
+	if n := d.count("UPDATE `mygrate_lock` SET expires"); n != refreshed {
+		t.Fatalf(`expected no refresh after unlock, got '%d' more`, n-refreshed)
+	}
+	if d.count("DELETE FROM `mygrate_lock` WHERE id = 1 AND owner") != 1 {
+		t.Fatal(`expected the lock to be deleted`)
+	}
+}
+
+func TestSQLStore_UpRecord_Checksum(t *testing.T) {
+	t.Parallel()
+
+	db, d := openRecordingDB(t)
+	s := NewSQLStore(db)
+
+	rec := Record{ID: "1", Executed: time.Now(), Started: time.Now(), Checksum: "abc"}
+	if err := s.UpRecord(context.Background(), rec); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	// the checksum is part of the insert, so a transaction records both atomically
+	if len(d.execs) != 1 || d.execs[0] != s.qry.up {
+		t.Fatalf(`expected only the insert, got '%v'`, d.execs)
+	}
+	if args := d.args[0]; len(args) != 8 || args[2] != "abc" {
+		t.Fatalf(`expected the checksum as third argument, got '%v'`, args)
+	}
+}
+
+// lockDriver is a database/sql driver which emulates the lock table and the
+// MySQL advisory lock, so that stores can compete for the lock.
+type lockDriver struct {
+	mu        sync.Mutex
+	owner     string // owner of the lock row, empty if there is none
+	expires   time.Time
+	advisory  bool // the advisory lock is held
+	refreshes int
+}
+
+func (d *lockDriver) Open(string) (driver.Conn, error) { return lockConn{d: d}, nil }
+
+// setRow replaces the lock row and returns the number of refreshes so far.
+func (d *lockDriver) setRow(owner string, expires time.Time) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.owner, d.expires = owner, expires
+	return d.refreshes
+}
+
+func (d *lockDriver) row() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.owner
+}
+
+func (d *lockDriver) refreshCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.refreshes
+}
+
+func (d *lockDriver) exec(query string, args []driver.Value) (driver.Result, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "DELETE FROM `mygrate_lock` WHERE id = 1 AND expires <"):
+		if d.owner != "" && d.expires.Before(args[0].(time.Time)) {
+			d.owner = ""
+			return driver.RowsAffected(1), nil
+		}
+	case strings.HasPrefix(query, "INSERT INTO `mygrate_lock`"):
+		if d.owner != "" {
+			return nil, errors.New("duplicate key")
+		}
+		d.owner, d.expires = args[0].(string), args[2].(time.Time)
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(query, "UPDATE `mygrate_lock` SET expires"):
+		d.refreshes++
+		if d.owner == args[1].(string) {
+			d.expires = args[0].(time.Time)
+			return driver.RowsAffected(1), nil
+		}
+	case strings.HasPrefix(query, "DELETE FROM `mygrate_lock` WHERE id = 1 AND owner"):
+		if d.owner == args[0].(string) {
+			d.owner = ""
+			return driver.RowsAffected(1), nil
+		}
+	case strings.HasPrefix(query, "SELECT RELEASE_LOCK"):
+		d.advisory = false
+	default:
+		return nil, fmt.Errorf("unexpected exec '%s'", query)
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (d *lockDriver) query(query string) (driver.Rows, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "SELECT owner FROM `mygrate_lock`"):
+		if d.owner == "" {
+			return &lockRows{col: "owner"}, nil
+		}
+		return &lockRows{col: "owner", vals: []driver.Value{d.owner}}, nil
+	case strings.HasPrefix(query, "SELECT GET_LOCK"):
+		if d.advisory {
+			return &lockRows{col: "acquired", vals: []driver.Value{int64(0)}}, nil
+		}
+		d.advisory = true
+		return &lockRows{col: "acquired", vals: []driver.Value{int64(1)}}, nil
+	}
+	return nil, fmt.Errorf("unexpected query '%s'", query)
+}
+
+type lockConn struct{ d *lockDriver }
+
+func (c lockConn) Prepare(query string) (driver.Stmt, error) {
+	return lockStmt{d: c.d, query: query}, nil
+}
+func (c lockConn) Close() error              { return nil }
+func (c lockConn) Begin() (driver.Tx, error) { return nil, errors.New("not implemented") }
+
+type lockStmt struct {
+	d     *lockDriver
+	query string
+}
+
+func (s lockStmt) Close() error  { return nil }
+func (s lockStmt) NumInput() int { return -1 }
+
+func (s lockStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.d.exec(s.query, args)
+}
+
+func (s lockStmt) Query([]driver.Value) (driver.Rows, error) {
+	return s.d.query(s.query)
+}
+
+// lockRows returns a single column with one row per value.
+type lockRows struct {
+	col  string
+	vals []driver.Value
+}
+
+func (r *lockRows) Columns() []string { return []string{r.col} }
+func (r *lockRows) Close() error      { return nil }
+
+func (r *lockRows) Next(dest []driver.Value) error {
+	if len(r.vals) == 0 {
+		return io.EOF
+	}
+	dest[0], r.vals = r.vals[0], r.vals[1:]
+	return nil
+}
+
+// openLockDB returns a *sql.DB backed by a new lockDriver.
+func openLockDB(t *testing.T) (*sql.DB, *lockDriver) {
+	t.Helper()
+
+	recordingDriverSeqMu.Lock()
+	recordingDriverSeq++
+	name := fmt.Sprintf("store-lock-%d", recordingDriverSeq)
+	recordingDriverSeqMu.Unlock()
+
+	d := &lockDriver{}
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+func TestSQLStore_Lock_Timeout(t *testing.T) {
+	t.Parallel()
+
+	db, d := openLockDB(t)
+	d.setRow("other", time.Now().Add(time.Hour))
+	s := NewSQLStore(db, WithLockTimeout(10*time.Millisecond), WithLockOwner("me"))
+
+	err := s.LockContext(context.Background())
+	var lerr *LockError
+	if !errors.As(err, &lerr) || !errors.Is(err, ErrLocked) {
+		t.Fatalf(`expected a LockError, got '%v'`, err)
+	}
+	if lerr.Owner != "other" || lerr.Resource != "table `mygrate_lock`" {
+		t.Fatalf(`expected the lock to be held by 'other', got '%+v'`, lerr)
+	}
+	if owner := d.row(); owner != "other" {
+		t.Fatalf(`expected the lock row to be kept, got owner '%s'`, owner)
+	}
+}
+
+func TestSQLStore_Lock_Expired(t *testing.T) {
+	t.Parallel()
+
+	db, d := openLockDB(t)
+	// the owner crashed and stopped refreshing the row
+	d.setRow("crashed", time.Now().UTC().Add(-time.Second))
+	s := NewSQLStore(db, WithLockTimeout(10*time.Millisecond), WithLockOwner("me"))
+
+	if err := s.LockContext(context.Background()); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if owner := d.row(); owner != "me" {
+		t.Fatalf(`expected the expired lock to be taken over, got owner '%s'`, owner)
+	}
+	if err := s.UnlockContext(context.Background()); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if owner := d.row(); owner != "" {
+		t.Fatalf(`expected the lock row to be deleted, got owner '%s'`, owner)
+	}
+}
+
+func TestSQLStore_Lock_Competing(t *testing.T) {
+	t.Parallel()
+
+	db, _ := openLockDB(t)
+	first := NewSQLStore(db, WithLockOwner("first"))
+	second := NewSQLStore(db, WithLockTimeout(10*time.Millisecond), WithLockOwner("second"))
+
+	if err := first.LockContext(context.Background()); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	var lerr *LockError
+	if err := second.LockContext(context.Background()); !errors.As(err, &lerr) || lerr.Owner != "first" {
+		t.Fatalf(`expected a LockError of 'first', got '%v'`, err)
+	}
+
+	if err := first.UnlockContext(context.Background()); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if err := second.LockContext(context.Background()); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if err := second.UnlockContext(context.Background()); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+}
+
+func TestSQLStore_Lock_Advisory(t *testing.T) {
+	t.Parallel()
+
+	db, d := openLockDB(t)
+	d.advisory = true
+	s := NewSQLStore(db,
+		WithLockTimeout(10*time.Millisecond),
+		WithLockOwner("me"),
+		WithAdvisoryLock(MySQLAdvisoryLock("mygrate")),
+	)
+
+	var lerr *LockError
+	if err := s.LockContext(context.Background()); !errors.As(err, &lerr) || lerr.Owner != "" {
+		t.Fatalf(`expected a LockError without owner, got '%v'`, err)
+	}
+	if owner := d.row(); owner != "" {
+		t.Fatalf(`expected no lock row without the advisory lock, got owner '%s'`, owner)
+	}
+
+	d.mu.Lock()
+	d.advisory = false
+	d.mu.Unlock()
+
+	if err := s.LockContext(context.Background()); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if err := s.UnlockContext(context.Background()); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.advisory || d.owner != "" {
+		t.Fatalf(`expected both locks to be released, got advisory '%t' and owner '%s'`, d.advisory, d.owner)
+	}
+}
+
+func TestSQLStore_Lock_Lost(t *testing.T) {
+	t.Parallel()
+
+	db, d := openLockDB(t)
+	s := NewSQLStore(db, WithLockTTL(30*time.Millisecond), WithLockOwner("me"))
+
+	if err := s.LockContext(context.Background()); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	// the row expired, e.g. while the database was unreachable, and was taken over
+	refreshes := d.setRow("other", time.Now().Add(time.Hour))
+	for i := 0; d.refreshCount() == refreshes; i++ {
+		if i == 1000 {
+			t.Fatal(`expected the lock to be refreshed`)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := s.UnlockContext(context.Background()); !errors.Is(err, ErrLockLost) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, ErrLockLost, err)
+	}
+	if owner := d.row(); owner != "other" {
+		t.Fatalf(`expected the lock of 'other' to be kept, got owner '%s'`, owner)
+	}
+}
+
+func TestSQLStore_Lock_SameStore(t *testing.T) {
+	t.Parallel()
+
+	db, _ := openLockDB(t)
+	s := NewSQLStore(db, WithLockTimeout(10*time.Millisecond), WithLockOwner("shared"))
+
+	if err := s.LockContext(context.Background()); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+
+	var lerr *LockError
+	if err := s.LockContext(context.Background()); !errors.As(err, &lerr) || lerr.Owner != "shared" {
+		t.Fatalf(`expected a LockError of 'shared', got '%v'`, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.LockContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, context.Canceled, err)
+	}
+
+	if err := s.UnlockContext(context.Background()); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+}
+
+func TestSQLStore_History_OptIn(t *testing.T) {
+	t.Parallel()
+
+	db, d := openRecordingDB(t)
+	s := NewSQLStore(db)
+	if err := s.InitContext(context.Background()); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if n := d.count(s.qry.historyCreate); n != 0 {
+		t.Fatalf(`expected no history table without WithHistory, got '%d' creates`, n)
+	}
+	if err := s.AppendHistory(context.Background(), HistoryEntry{}); !errors.Is(err, ErrHistoryUnsupported) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, ErrHistoryUnsupported, err)
+	}
+	if _, err := s.FindHistory(context.Background()); !errors.Is(err, ErrHistoryUnsupported) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, ErrHistoryUnsupported, err)
+	}
+
+	db, d = openRecordingDB(t)
+	s = NewSQLStore(db, WithHistory())
+	if err := s.InitContext(context.Background()); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+	if n := d.count(s.qry.historyCreate); n != 1 {
+		t.Fatalf(`expected the history table with WithHistory, got '%d' creates`, n)
+	}
+	if err := s.AppendHistory(context.Background(), HistoryEntry{}); err != nil {
+		t.Fatalf(`did not expected err '%s'`, err)
+	}
+}
@@ -2,10 +2,127 @@
 package store
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
 )
 
+const lockPollInterval = 500 * time.Millisecond
+
 var (
 	// ErrIDNotFound will be returned if ID is not found.
 	ErrIDNotFound = errors.New("id not found")
+	// ErrLocked will be returned if a lock is held by someone else.
+	ErrLocked = errors.New("lock is held by someone else")
+	// ErrLockLost will be returned by Unlock if the lock expired while it was held,
+	// so someone else may have acquired it in between.
+	ErrLockLost = errors.New("lock was lost before it was released")
+	// ErrHistoryUnsupported will be returned by AppendHistory and FindHistory
+	// if the history is not enabled, see WithHistory.
+	ErrHistoryUnsupported = errors.New("store does not support history")
 )
+
+// LockError will be returned if a lock could not be acquired in time.
+// It unwraps to ErrLocked.
+type LockError struct {
+	Resource string        // Resource is the locked table or file.
+	Owner    string        // Owner of the lock, empty if unknown.
+	Timeout  time.Duration // Timeout after which it was given up.
+}
+
+// Error makes this struct an error.
+func (e *LockError) Error() string {
+	owner := e.Owner
+	if owner == "" {
+		owner = "someone else"
+	}
+	return fmt.Sprintf("lock on %s is held by %s, gave up after %s", e.Resource, owner, e.Timeout)
+}
+
+// Unwrap implements errors.Unwrap.
+func (e *LockError) Unwrap() error {
+	return ErrLocked
+}
+
+// CorruptStateError describes a state file which could not be parsed.
+type CorruptStateError struct {
+	Path   string // Path of the corrupt state file.
+	Backup string // Backup which was restored, empty if there was no usable backup.
+	Err    error  // Err is the parse error of the state file.
+}
+
+// Error makes this struct an error.
+func (e *CorruptStateError) Error() string {
+	if e.Backup == "" {
+		return fmt.Sprintf("state file %s is corrupt and no usable backup was found: %s", e.Path, e.Err)
+	}
+	return fmt.Sprintf("state file %s is corrupt, restored from %s: %s", e.Path, e.Backup, e.Err)
+}
+
+// Unwrap implements errors.Unwrap.
+func (e *CorruptStateError) Unwrap() error {
+	return e.Err
+}
+
+// semaphore is the in-process part of a lock, unlike a mutex it can be given up
+// on, so that ctx and the lock timeout also cover stores shared by services.
+type semaphore chan struct{}
+
+func newSemaphore() semaphore {
+	return make(semaphore, 1)
+}
+
+// tryAcquire acquires the semaphore without waiting and reports if it was acquired.
+func (s semaphore) tryAcquire() bool {
+	select {
+	case s <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release releases the semaphore, it does nothing if it is not acquired.
+func (s semaphore) release() {
+	select {
+	case <-s:
+	default:
+	}
+}
+
+// waitLock calls try until it acquires the lock, the deadline is exceeded or ctx is done.
+// try reports if the lock was acquired and, if known, the current owner.
+func waitLock(
+	ctx context.Context,
+	deadline time.Time,
+	resource string,
+	timeout time.Duration,
+	try func() (bool, string, error),
+) error {
+	for {
+		acquired, owner, err := try()
+		if err != nil {
+			return err
+		}
+		if acquired {
+			return nil
+		}
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return &LockError{Resource: resource, Owner: owner, Timeout: timeout}
+		}
+		if wait > lockPollInterval {
+			wait = lockPollInterval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Package tracing adapts an OpenTelemetry-style tracer to mygrate.Tracer,
+// without depending on OpenTelemetry itself. The tracer is plugged in with a
+// small shim, e.g. for go.opentelemetry.io/otel:
+//
+//	type otelSpan struct{ span trace.Span }
+//
+//	func (s otelSpan) SetAttribute(key string, value interface{}) {
+//		s.span.SetAttributes(attribute.String(key, fmt.Sprint(value)))
+//	}
+//	func (s otelSpan) RecordError(err error)       { s.span.RecordError(err) }
+//	func (s otelSpan) SetError(description string) { s.span.SetStatus(codes.Error, description) }
+//	func (s otelSpan) End()                        { s.span.End() }
+//
+//	tracer := tracing.New(func(ctx context.Context, name string) (context.Context, tracing.Span) {
+//		ctx, span := otel.Tracer("mygrate").Start(ctx, name)
+//		return ctx, otelSpan{span}
+//	})
+//	myg := mygrate.New(mygrate.WithTracer(tracer))
+package tracing
+
+import (
+	"context"
+
+	"github.com/lanz-dev/go-mygrate/mygrate"
+)
+
+// Span is the subset of an OpenTelemetry span, which is used by the adapter.
+type Span interface {
+	// SetAttribute sets an attribute of the span.
+	SetAttribute(key string, value interface{})
+	// RecordError records err as an event of the span.
+	RecordError(err error)
+	// SetError sets the status of the span to error.
+	SetError(description string)
+	// End ends the span.
+	End()
+}
+
+// StartFunc starts a span of the supplied tracer, the returned context carries the span.
+type StartFunc func(ctx context.Context, name string) (context.Context, Span)
+
+// Option configures the adapter.
+type Option func(t *tracer)
+
+// WithAttributes adds attributes to every span, e.g. the name of the service.
+func WithAttributes(attrs ...mygrate.Attribute) Option {
+	return func(t *tracer) {
+		t.attrs = append(t.attrs, attrs...)
+	}
+}
+
+type tracer struct {
+	start StartFunc
+	attrs []mygrate.Attribute
+}
+
+// New returns a mygrate.Tracer, which starts its spans with start.
+func New(start StartFunc, opts ...Option) mygrate.Tracer {
+	t := &tracer{start: start}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// Start implements mygrate.Tracer.
+func (t *tracer) Start(ctx context.Context, name string, attrs ...mygrate.Attribute) (context.Context, mygrate.Span) {
+	ctx, span := t.start(ctx, name)
+	for _, attr := range t.attrs {
+		span.SetAttribute(attr.Key, attr.Value)
+	}
+	for _, attr := range attrs {
+		span.SetAttribute(attr.Key, attr.Value)
+	}
+	return ctx, adapter{span: span}
+}
+
+type adapter struct {
+	span Span
+}
+
+// End implements mygrate.Span.
+func (a adapter) End(err error) {
+	if err != nil {
+		a.span.RecordError(err)
+		a.span.SetError(err.Error())
+	}
+	a.span.End()
+}
@@ -0,0 +1,79 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lanz-dev/go-mygrate/mygrate"
+	"github.com/lanz-dev/go-mygrate/store"
+	"github.com/lanz-dev/go-mygrate/tracing"
+)
+
+var errUnitTest = errors.New("unittest")
+
+type spanKey struct{}
+
+// span records the calls of the adapter.
+type span struct {
+	name  string
+	attrs map[string]interface{}
+	err   string
+	ended bool
+}
+
+func (s *span) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *span) RecordError(err error)                      { s.err = err.Error() }
+func (s *span) SetError(description string)                {}
+func (s *span) End()                                       { s.ended = true }
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	var spans []*span
+	tracer := tracing.New(func(ctx context.Context, name string) (context.Context, tracing.Span) {
+		sp := &span{name: name, attrs: map[string]interface{}{}}
+		spans = append(spans, sp)
+		return context.WithValue(ctx, spanKey{}, sp), sp
+	}, tracing.WithAttributes(mygrate.Attribute{Key: "service", Value: "unittest"}))
+
+	m := mygrate.New(mygrate.WithStore(store.NewMemoryStore()), mygrate.WithTracer(tracer))
+	var parent string
+	m.RegisterContext("1", func(ctx context.Context) error {
+		parent = ctx.Value(spanKey{}).(*span).name
+		return nil
+	}, nil)
+	m.Register("2", func() error { return errUnitTest }, nil)
+
+	if _, err := m.Migrate(false); !errors.Is(err, errUnitTest) {
+		t.Fatalf(`expected err to be '%s', got '%v'`, errUnitTest, err)
+	}
+
+	var names []string
+	for _, sp := range spans {
+		names = append(names, sp.name)
+		if !sp.ended || sp.attrs["service"] != "unittest" {
+			t.Fatalf(`expected span '%s' to be ended with the default attributes, got '%+v'`, sp.name, sp)
+		}
+	}
+	expected := []string{
+		mygrate.SpanInit, mygrate.SpanLock, mygrate.SpanFindDone,
+		mygrate.SpanUp, mygrate.SpanStoreUp, mygrate.SpanUp,
+	}
+	if fmt.Sprint(names) != fmt.Sprint(expected) {
+		t.Fatalf(`expected spans '%v', got '%v'`, expected, names)
+	}
+
+	if parent != mygrate.SpanUp {
+		t.Fatalf(`expected the up func to run inside span '%s', got '%s'`, mygrate.SpanUp, parent)
+	}
+	up := spans[3]
+	if up.attrs[mygrate.AttrID] != "1" || up.attrs[mygrate.AttrDirection] != "up" {
+		t.Fatalf(`expected the id and direction attributes, got '%v'`, up.attrs)
+	}
+	if failed := spans[5]; !strings.Contains(failed.err, errUnitTest.Error()) {
+		t.Fatalf(`expected the failed span to record err '%s', got '%s'`, errUnitTest, failed.err)
+	}
+}